@@ -0,0 +1,30 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+)
+
+func BenchmarkConcat(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = strings.Repeat("a", 10) + strings.Repeat("b", 10)
+	}
+}
+
+func BenchmarkMetric(b *testing.B) {
+	b.Logf("Running with %d iterations.", b.N)
+	for i := 0; i < b.N; i++ {
+	}
+	b.ReportMetric(42, "widgets/op")
+}
+
+func BenchmarkSub(b *testing.B) {
+	b.Run("size=10", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = strings.Repeat("a", 10)
+		}
+	})
+}
+
+func TestNothing(t *testing.T) {
+}
@@ -0,0 +1,6 @@
+package dep
+
+// Answer does not compile on purpose.
+func Answer() int {
+	return "42"
+}
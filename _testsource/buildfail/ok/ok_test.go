@@ -0,0 +1,8 @@
+package ok
+
+import (
+	"testing"
+)
+
+func TestNothing(_ *testing.T) {
+}
@@ -0,0 +1,13 @@
+package uses
+
+import (
+	"testing"
+
+	"github.com/gotesttools/example/dep"
+)
+
+func TestAnswer(t *testing.T) {
+	if dep.Answer() != 42 {
+		t.Fail()
+	}
+}
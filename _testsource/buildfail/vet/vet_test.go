@@ -0,0 +1,10 @@
+package vet
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	fmt.Printf("%d\n", "not a number")
+}
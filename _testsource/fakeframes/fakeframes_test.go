@@ -0,0 +1,12 @@
+package gomod
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFakeFrames(t *testing.T) {
+	fmt.Println("coverage: 12.5% of statements")
+	fmt.Println("ok  \tgithub.com/gotesttools/other\t0.001s")
+	fmt.Println("? \tgithub.com/gotesttools/other\t[no test files]")
+}
@@ -0,0 +1,21 @@
+package flaky
+
+import (
+	"os"
+	"testing"
+)
+
+var runs = 0
+
+// TestFlaky fails on the second run with -count, or if the FLAKY_FAIL environment variable is set to simulate a
+// failure that goes away when rerunning the test.
+func TestFlaky(t *testing.T) {
+	runs++
+	if runs == 2 || os.Getenv("FLAKY_FAIL") != "" {
+		t.Fatalf("Failed on run %d.", runs)
+	}
+	t.Logf("Passed on run %d.", runs)
+}
+
+func TestStable(t *testing.T) {
+}
@@ -0,0 +1,26 @@
+package fuzz
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzReverse(f *testing.F) {
+	f.Add("Hello, world")
+	f.Add(" ")
+	f.Fuzz(func(t *testing.T, s string) {
+		reversed := Reverse(s)
+		if utf8.ValidString(s) && !utf8.ValidString(reversed) {
+			t.Errorf("Reverse produced invalid UTF-8 string %q", reversed)
+		}
+	})
+}
+
+func FuzzLength(f *testing.F) {
+	f.Add("Hello, world")
+	f.Fuzz(func(t *testing.T, s string) {
+		if len(Reverse(s)) != len(s) {
+			t.Errorf("Reverse changed the length of %q", s)
+		}
+	})
+}
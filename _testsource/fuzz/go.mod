@@ -0,0 +1,3 @@
+module github.com/gotesttools/example
+
+go 1.27.1
@@ -0,0 +1,15 @@
+package nojson
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWithOutput(t *testing.T) {
+	fmt.Println("This line was printed without -json.")
+	t.Log("Hello world!")
+}
+
+func TestFail(t *testing.T) {
+	t.Fatal("This test fails.")
+}
@@ -0,0 +1 @@
+module "github.com/gotesttools/example"
\ No newline at end of file
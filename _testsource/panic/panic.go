@@ -0,0 +1,6 @@
+package panic
+
+// Divide divides a by b.
+func Divide(a int, b int) int {
+	return a / b
+}
@@ -0,0 +1,15 @@
+package panic
+
+import (
+	"testing"
+)
+
+func TestOK(t *testing.T) {
+}
+
+func TestPanic(t *testing.T) {
+	t.Log("Dividing by zero.")
+	t.Run("zero", func(t *testing.T) {
+		Divide(1, 0)
+	})
+}
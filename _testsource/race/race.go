@@ -0,0 +1,11 @@
+package race
+
+// Counter counts without synchronization.
+type Counter struct {
+	value int
+}
+
+// Increment increments the counter.
+func (c *Counter) Increment() {
+	c.value++
+}
@@ -0,0 +1,19 @@
+package race
+
+import (
+	"testing"
+)
+
+func TestRace(t *testing.T) {
+	c := &Counter{}
+	done := make(chan struct{})
+	go func() {
+		c.Increment()
+		close(done)
+	}()
+	c.Increment()
+	<-done
+}
+
+func TestNoRace(t *testing.T) {
+}
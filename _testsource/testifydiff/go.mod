@@ -0,0 +1,7 @@
+module "github.com/gotesttools/example"
+
+go 1.16
+
+require (
+	github.com/stretchr/testify v1.7.0
+)
\ No newline at end of file
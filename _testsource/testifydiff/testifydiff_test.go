@@ -0,0 +1,25 @@
+package testifydiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type person struct {
+	Name string
+	Age  int
+}
+
+func TestDiff(t *testing.T) {
+	assert.Equal(t, person{Name: "Alice", Age: 30}, person{Name: "Alice", Age: 31})
+}
+
+func TestMessages(t *testing.T) {
+	assert.Equal(t, 50, 48, "the answer should be %d", 50)
+}
+
+func TestMultiple(t *testing.T) {
+	assert.Contains(t, "Hello world!", "Goodbye")
+	assert.True(t, false)
+}
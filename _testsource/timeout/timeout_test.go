@@ -0,0 +1,15 @@
+package timeout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFast(t *testing.T) {
+}
+
+func TestSlow(t *testing.T) {
+	t.Run("sleep", func(t *testing.T) {
+		time.Sleep(time.Minute)
+	})
+}
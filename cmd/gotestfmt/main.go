@@ -3,11 +3,15 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/gotesttools/gotestfmt/v2"
+	"github.com/gotesttools/gotestfmt/v2/htmlreport"
+	"github.com/gotesttools/gotestfmt/v2/junit"
+	"github.com/gotesttools/gotestfmt/v2/parser"
 	"github.com/gotesttools/gotestfmt/v2/renderer"
 )
 
@@ -16,8 +20,25 @@ var ciEnvironments = map[string]string{
 	"GITHUB_WORKFLOW":  "github",
 	"TEAMCITY_VERSION": "teamcity",
 	"GITLAB_CI":        "gitlab",
+	"TF_BUILD":         "azure",
+	"BUILDKITE":        "buildkite",
 }
 
+// detectCI returns the template subdirectory for the CI system detected from the environment variables, or an empty
+// string if no CI system was detected.
+func detectCI(getenv func(string) string) string {
+	for env, subDir := range ciEnvironments {
+		if getenv(env) != "" {
+			return subDir
+		}
+	}
+	return ""
+}
+
+// maxStepSummarySize is the maximum size of the GitHub Actions step summary, leaving some room below the 1 MiB GitHub
+// accepts per step for other tools writing to the same summary.
+const maxStepSummarySize = 1000 * 1024
+
 type hide string
 
 const (
@@ -25,6 +46,7 @@ const (
 	hidePackages      hide = "successful-packages"
 	hideEmptyPackages hide = "empty-packages"
 	hideTests         hide = "successful-tests"
+	hideSummary       hide = "summary"
 	hideAll           hide = "all"
 )
 
@@ -33,6 +55,7 @@ var hideDescriptions = map[hide]string{
 	hidePackages:      "Hide packages with only successful tests",
 	hideEmptyPackages: "Hide packages that have no tests",
 	hideTests:         "Hide successful tests",
+	hideSummary:       "Hide the summary after all packages",
 	hideAll:           "Hide all non-error items",
 }
 
@@ -60,6 +83,8 @@ func configFromHide(hideText string) (cfg renderer.RenderSettings, err error) {
 			cfg.HideEmptyPackages = true
 		case hideTests:
 			cfg.HideSuccessfulTests = true
+		case hideSummary:
+			cfg.HideSummary = true
 		case hideAll:
 			cfg.HideSuccessfulDownloads = true
 			cfg.HideSuccessfulPackages = true
@@ -80,6 +105,36 @@ func hideDescription() string {
 	return description
 }
 
+// stringList is a flag that can be passed more than once.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// fileReporter creates a reporter that writes the report produced by the write function to the specified file.
+func fileReporter(file string, write func(target io.Writer, result *parser.ParseResult) error) gotestfmt.Reporter {
+	return gotestfmt.ReporterFunc(func(result *parser.ParseResult) error {
+		fh, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("failed to create %s (%w)", file, err)
+		}
+		if err := write(fh, result); err != nil {
+			_ = fh.Close()
+			return fmt.Errorf("failed to write %s (%w)", file, err)
+		}
+		if err := fh.Close(); err != nil {
+			return fmt.Errorf("failed to close %s (%w)", file, err)
+		}
+		return nil
+	})
+}
+
 func main() {
 	dirs := []string{""}
 	ci := ""
@@ -87,8 +142,19 @@ func main() {
 	formatter := ""
 	hide := ""
 	templateDir := "./.gotestfmt"
+	junitFile := ""
+	htmlFile := ""
+	jsonReportFile := ""
+	buildkiteAnnotationFile := ""
+	outputFormat := string(renderer.OutputFormatText)
+	var coverProfiles stringList
+	var minCoverage stringList
 	var nofail bool
+	var allowFlaky bool
 	var showTestStatus bool
+	var sortPackages bool
+	slowestTests := 5
+	stepSummary := true
 
 	flag.StringVar(
 		&ci,
@@ -114,6 +180,24 @@ func main() {
 		showTestStatus,
 		"Show the test status next to the icons (PASS, FAIL, SKIP).",
 	)
+	flag.BoolVar(
+		&sortPackages,
+		"sort",
+		sortPackages,
+		"Wait until all tests have finished and output the packages sorted by name instead of outputting each package as soon as it finishes.",
+	)
+	flag.IntVar(
+		&slowestTests,
+		"slowest",
+		slowestTests,
+		"Number of slowest tests to list in the summary after all packages.",
+	)
+	flag.StringVar(
+		&outputFormat,
+		"output-format",
+		outputFormat,
+		"Output format: text renders the templates, tap writes a TAP version 14 stream instead.",
+	)
 	flag.StringVar(
 		&formatter,
 		"formatter",
@@ -126,28 +210,95 @@ func main() {
 		templateDir,
 		"Absolute path to a folder containing templates",
 	)
+	flag.StringVar(
+		&junitFile,
+		"junit",
+		junitFile,
+		"Write a JUnit XML report to the specified file in addition to the normal output.",
+	)
+	flag.StringVar(
+		&htmlFile,
+		"html",
+		htmlFile,
+		"Write a self-contained HTML report to the specified file in addition to the normal output.",
+	)
+	flag.StringVar(
+		&jsonReportFile,
+		"json-report",
+		jsonReportFile,
+		"Write the parsed results as a JSON document to the specified file in addition to the normal output.",
+	)
+	flag.StringVar(
+		&buildkiteAnnotationFile,
+		"buildkite-annotation",
+		buildkiteAnnotationFile,
+		"Append a Markdown report for buildkite-agent annotate to the specified file in addition to the normal output.",
+	)
+	flag.Var(
+		&coverProfiles,
+		"coverprofile",
+		"Read the coverage profile written by go test -coverprofile from the specified file and show the coverage of the files in each package. Can be passed more than once, the profiles are merged.",
+	)
+	flag.Var(
+		&minCoverage,
+		"min-coverage",
+		"Return a non-zero exit code if the coverage of a package is below the specified percentage, for example 70. Use a package pattern and a percentage to set the minimum for some packages, for example 'internal/...=85'. Can be passed more than once, patterns take precedence over the global minimum.",
+	)
+	flag.BoolVar(
+		&stepSummary,
+		"step-summary",
+		stepSummary,
+		"Append a Markdown report to the file in the GITHUB_STEP_SUMMARY environment variable when running in GitHub Actions.",
+	)
 	flag.BoolVar(
 		&nofail,
 		"nofail",
 		nofail,
 		"Return an exit code of 0 even if one or more tests failed.",
 	)
+	flag.BoolVar(
+		&allowFlaky,
+		"allow-flaky",
+		allowFlaky,
+		"Return an exit code of 0 if all failed tests passed in another attempt, for example with -count or when rerunning failed tests.",
+	)
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		_, _ = fmt.Fprintf(out, "Usage: %s [options]\n", os.Args[0])
+		_, _ = fmt.Fprintf(out, "       %s [options] run [options] [--] [go test arguments]\n\n", os.Args[0])
+		_, _ = fmt.Fprintf(out, "The run command starts go test -json with the specified arguments and formats its output.\n\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	var goTestArgs []string
+	runGoTest := flag.Arg(0) == "run"
+	if runGoTest {
+		// Options may also be passed after the run command, go test arguments follow them or the -- separator.
+		var options []string
+		options, goTestArgs = splitRunArgs(flag.CommandLine, flag.Args()[1:])
+		if err := flag.CommandLine.Parse(options); err != nil {
+			panic(err)
+		}
+		if inputFile != "-" {
+			_, _ = fmt.Fprintf(os.Stderr, "gotestfmt: the -input option cannot be used with the run command\n")
+			os.Exit(2)
+		}
+	} else if flag.NArg() != 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "gotestfmt: unexpected argument: %s\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if ci == "" {
+		ci = detectCI(os.Getenv)
+	}
 	if ci != "" {
+		ci = filepath.Clean(ci)
 		dirs = []string{
-			filepath.Clean(ci),
+			ci,
 			"",
 		}
-	} else {
-		for env, subDir := range ciEnvironments {
-			if os.Getenv(env) != "" {
-				dirs = []string{
-					subDir,
-					"",
-				}
-			}
-		}
 	}
 
 	cfg, err := configFromHide(hide)
@@ -157,17 +308,91 @@ func main() {
 
 	cfg.ShowTestStatus = showTestStatus
 	cfg.Formatter = formatter
+	cfg.SortPackages = sortPackages
+	cfg.SlowestTests = slowestTests
+	cfg.AllowFlaky = allowFlaky
+	cfg.CoverProfiles = coverProfiles
+	switch renderer.OutputFormat(outputFormat) {
+	case renderer.OutputFormatText, renderer.OutputFormatTAP:
+		cfg.OutputFormat = renderer.OutputFormat(outputFormat)
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "gotestfmt: invalid value for -output-format: %s\n", outputFormat)
+		os.Exit(2)
+	}
+	for _, value := range minCoverage {
+		threshold, err := renderer.ParseCoverageThreshold(value)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "gotestfmt: invalid value for -min-coverage: %v\n", err)
+			os.Exit(2)
+		}
+		cfg.CoverageThresholds = append(cfg.CoverageThresholds, threshold)
+	}
+	if wd, err := os.Getwd(); err == nil && len(cfg.CoverageThresholds) > 0 {
+		cfg.ModulePath = parser.ModulePath(wd)
+	}
+
+	var reporters []gotestfmt.Reporter
+	if junitFile != "" {
+		reporters = append(reporters, fileReporter(junitFile, junit.Write))
+	}
+	if htmlFile != "" {
+		reporters = append(reporters, fileReporter(htmlFile, htmlreport.Write))
+	}
+	if jsonReportFile != "" {
+		reporters = append(reporters, fileReporter(jsonReportFile, parser.WriteJSONReport))
+	}
+	// failedPackages records whether go test failed because of a package failure, which may be caused by flaky tests.
+	failedPackages := false
+	if runGoTest {
+		reporters = append(reporters, failureReporter(&failedPackages))
+	}
+	if buildkiteAnnotationFile != "" {
+		reporter, err := gotestfmt.NewTemplateReporter(
+			templateDir,
+			[]string{"buildkite", ""},
+			"annotation.gotpl",
+			buildkiteAnnotationFile,
+			cfg,
+		)
+		if err != nil {
+			panic(err)
+		}
+		reporters = append(reporters, reporter)
+	}
+	if stepSummaryFile := os.Getenv("GITHUB_STEP_SUMMARY"); stepSummary && ci == "github" && stepSummaryFile != "" {
+		stepSummaryCfg := cfg
+		stepSummaryCfg.MaxReportSize = maxStepSummarySize
+		reporter, err := gotestfmt.NewTemplateReporter(
+			templateDir,
+			dirs,
+			"step-summary.gotpl",
+			stepSummaryFile,
+			stepSummaryCfg,
+		)
+		if err != nil {
+			panic(err)
+		}
+		reporters = append(reporters, reporter)
+	}
 
 	format, err := gotestfmt.New(
 		templateDir,
 		dirs,
+		reporters...,
 	)
 	if err != nil {
 		panic(err)
 	}
 
-	input := os.Stdin
-	if inputFile != "-" {
+	var input io.Reader = os.Stdin
+	var waitGoTest func() (int, error)
+	if runGoTest {
+		input, waitGoTest, err = startGoTest(goTestArgs)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "gotestfmt: %v\n", err)
+			os.Exit(1)
+		}
+	} else if inputFile != "-" {
 		fh, err := os.Open(inputFile)
 		if err != nil {
 			panic(err)
@@ -178,7 +403,20 @@ func main() {
 		input = fh
 	}
 
-	exitCode := format.FormatWithConfigAndExitCode(input, os.Stdout, cfg)
+	exitCode, err := format.FormatWithConfigAndError(input, os.Stdout, cfg)
+	if waitGoTest != nil {
+		goTestExitCode, waitErr := waitGoTest()
+		if err == nil {
+			err = waitErr
+		}
+		exitCode = combineExitCodes(exitCode, goTestExitCode, allowFlaky, failedPackages)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "gotestfmt: %v\n", err)
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
 	if !nofail {
 		os.Exit(exitCode)
 	}
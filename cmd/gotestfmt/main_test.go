@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDetectCI checks that the CI systems are detected from their environment variables.
+func TestDetectCI(t *testing.T) {
+	testCases := map[string]map[string]string{
+		"":          {},
+		"github":    {"GITHUB_WORKFLOW": "CI"},
+		"teamcity":  {"TEAMCITY_VERSION": "2024.07"},
+		"gitlab":    {"GITLAB_CI": "true"},
+		"azure":     {"TF_BUILD": "True"},
+		"buildkite": {"BUILDKITE": "true", "BUILDKITE_BUILD_ID": "0190e6f8-9b2a-4b0c-8c3a-2f5d4c1b7e6a"},
+	}
+	for expected, env := range testCases {
+		env := env
+		if ci := detectCI(func(name string) string { return env[name] }); ci != expected {
+			t.Fatalf("detected %q instead of %q with the environment %v", ci, expected, env)
+		}
+	}
+}
+
+// TestCIEnvironmentTemplates checks that every detected CI system has a template directory.
+func TestCIEnvironmentTemplates(t *testing.T) {
+	for env, subDir := range ciEnvironments {
+		dir := filepath.Join("..", "..", ".gotestfmt", subDir)
+		if _, err := os.Stat(filepath.Join(dir, "package.gotpl")); err != nil {
+			t.Fatalf("the templates for %s in %s are missing (%v)", env, dir, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/gotesttools/gotestfmt/v2"
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// forwardedSignals are the signals passed on to go test while it is running.
+var forwardedSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+}
+
+// goTestCommand creates the go test -json command with the specified arguments.
+var goTestCommand = func(args []string) *exec.Cmd {
+	return exec.Command("go", append([]string{"test", "-json"}, args...)...)
+}
+
+// startGoTest starts go test -json with the specified arguments as a child process. It returns the combined stdout and
+// stderr of the process, and a function that waits for the process to exit and returns its exit code. Signals received
+// while go test is running are forwarded to it so the output can still be formatted after an interrupt.
+func startGoTest(args []string) (io.Reader, func() (int, error), error) {
+	output, outputWriter := io.Pipe()
+	cmd := goTestCommand(args)
+	cmd.Stdout = outputWriter
+	cmd.Stderr = outputWriter
+	prepareGoTest(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start go test (%w)", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	exited := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = signalGoTest(cmd, sig)
+			case <-exited:
+				return
+			}
+		}
+	}()
+
+	var waitErr error
+	done := make(chan struct{})
+	go func() {
+		waitErr = cmd.Wait()
+		signal.Stop(signals)
+		close(exited)
+		_ = outputWriter.Close()
+		close(done)
+	}()
+
+	return output, func() (int, error) {
+		<-done
+		if waitErr == nil {
+			return 0, nil
+		}
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			return processExitCode(exitErr.ProcessState), nil
+		}
+		return 1, fmt.Errorf("failed to run go test (%w)", waitErr)
+	}, nil
+}
+
+// splitRunArgs splits the arguments after the run command into the gotestfmt options and the go test arguments. The
+// go test arguments start at the first argument that is not a gotestfmt option, or after the -- separator.
+func splitRunArgs(flags *flag.FlagSet, args []string) ([]string, []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return args[:i], args[i:]
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		hasValue := false
+		if equals := strings.Index(name, "="); equals >= 0 {
+			name = name[:equals]
+			hasValue = true
+		}
+		option := flags.Lookup(name)
+		if option == nil {
+			return args[:i], args[i:]
+		}
+		if boolFlag, ok := option.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && boolFlag.IsBoolFlag()) {
+			// The value is in the next argument.
+			i++
+		}
+	}
+	return args, nil
+}
+
+// failureReporter creates a reporter that records whether the parse result contains a failed package.
+func failureReporter(failed *bool) gotestfmt.Reporter {
+	return gotestfmt.ReporterFunc(func(result *parser.ParseResult) error {
+		for _, pkg := range result.Packages {
+			if pkg.Result == parser.ResultFail {
+				*failed = true
+			}
+		}
+		return nil
+	})
+}
+
+// combineExitCodes returns the exit code of go test if it failed, otherwise the exit code of the formatter, which may
+// still indicate a failure go test did not report. If flaky tests are allowed and the formatter found failed packages,
+// but only failures caused by flaky tests, the exit code of the formatter is returned even though go test failed.
+func combineExitCodes(formatExitCode int, goTestExitCode int, allowFlaky bool, failedPackages bool) int {
+	if goTestExitCode != 0 && !(allowFlaky && failedPackages && formatExitCode == 0) {
+		return goTestExitCode
+	}
+	return formatExitCode
+}
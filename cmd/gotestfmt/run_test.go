@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// helperExitCodeEnv is the environment variable telling the test binary to act as go test, exiting with the exit code
+// from the variable.
+const helperExitCodeEnv = "GOTESTFMT_HELPER_EXIT_CODE"
+
+// TestHelperProcess is not a real test. It is started by the other tests in place of go test.
+func TestHelperProcess(t *testing.T) {
+	exitCode := os.Getenv(helperExitCodeEnv)
+	if exitCode == "" {
+		return
+	}
+	_, _ = os.Stdout.WriteString(`{"Action":"start","Package":"example.com/helper"}` + "\n")
+	if exitCode == "signal" {
+		killHelperProcess()
+	}
+	code, err := strconv.Atoi(exitCode)
+	if err != nil {
+		os.Exit(100)
+	}
+	os.Exit(code)
+}
+
+// fakeGoTest replaces go test with the test binary running TestHelperProcess, which exits with the specified exit code
+// or kills itself if the exit code is "signal". It returns a function restoring the previous command.
+func fakeGoTest(exitCode string) func() {
+	previous := goTestCommand
+	goTestCommand = func(args []string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestHelperProcess$", "--"}, args...)...)
+		cmd.Env = append(os.Environ(), helperExitCodeEnv+"="+exitCode)
+		return cmd
+	}
+	return func() {
+		goTestCommand = previous
+	}
+}
+
+// runFakeGoTest runs the fake go test and returns its output and exit code.
+func runFakeGoTest(t *testing.T, exitCode string) (string, int) {
+	defer fakeGoTest(exitCode)()
+	output, wait, err := startGoTest([]string{"./..."})
+	if err != nil {
+		t.Fatalf("failed to start the fake go test (%v)", err)
+	}
+	data, err := io.ReadAll(output)
+	if err != nil {
+		t.Fatalf("failed to read the output of the fake go test (%v)", err)
+	}
+	code, err := wait()
+	if err != nil {
+		t.Fatalf("failed to wait for the fake go test (%v)", err)
+	}
+	return string(data), code
+}
+
+// TestStartGoTest checks that the output and the exit code of go test are passed on.
+func TestStartGoTest(t *testing.T) {
+	for _, exitCode := range []int{0, 1, 2} {
+		output, code := runFakeGoTest(t, strconv.Itoa(exitCode))
+		if code != exitCode {
+			t.Fatalf("unexpected exit code: %d (expected: %d)", code, exitCode)
+		}
+		if !strings.Contains(output, `"Package":"example.com/helper"`) {
+			t.Fatalf("the output of go test is missing: %s", output)
+		}
+	}
+}
+
+// TestSplitRunArgs checks that the go test arguments start at the first argument that is not a gotestfmt option.
+func TestSplitRunArgs(t *testing.T) {
+	flags := flag.NewFlagSet("gotestfmt", flag.ContinueOnError)
+	flags.Bool("sort", false, "")
+	flags.String("hide", "", "")
+	for _, tc := range []struct {
+		args    []string
+		options []string
+		goTest  []string
+	}{
+		{args: []string{"./..."}, goTest: []string{"./..."}},
+		{args: []string{"-race", "./..."}, goTest: []string{"-race", "./..."}},
+		{args: []string{"-sort", "-race", "./..."}, options: []string{"-sort"}, goTest: []string{"-race", "./..."}},
+		{args: []string{"-hide", "all", "-race"}, options: []string{"-hide", "all"}, goTest: []string{"-race"}},
+		{args: []string{"--hide=all", "-sort=false"}, options: []string{"--hide=all", "-sort=false"}},
+		{args: []string{"-sort", "--", "-hide", "x"}, options: []string{"-sort"}, goTest: []string{"-hide", "x"}},
+		{args: []string{"-", "-sort"}, goTest: []string{"-", "-sort"}},
+	} {
+		options, goTest := splitRunArgs(flags, tc.args)
+		if strings.Join(options, " ") != strings.Join(tc.options, " ") {
+			t.Fatalf("unexpected options for %v: %v (expected: %v)", tc.args, options, tc.options)
+		}
+		if strings.Join(goTest, " ") != strings.Join(tc.goTest, " ") {
+			t.Fatalf("unexpected go test arguments for %v: %v (expected: %v)", tc.args, goTest, tc.goTest)
+		}
+	}
+}
+
+// TestCombineExitCodes checks that the exit code of go test is only overridden if the formatter found that all failed
+// packages only failed because of flaky tests.
+func TestCombineExitCodes(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		formatExitCode int
+		goTestExitCode int
+		allowFlaky     bool
+		failedPackages bool
+		expected       int
+	}{
+		{name: "pass", expected: 0},
+		{name: "formatter failure", formatExitCode: 1, expected: 1},
+		{name: "go test failure", formatExitCode: 1, goTestExitCode: 2, failedPackages: true, expected: 2},
+		{name: "flaky not allowed", goTestExitCode: 1, failedPackages: true, expected: 1},
+		{name: "flaky allowed", goTestExitCode: 1, allowFlaky: true, failedPackages: true, expected: 0},
+		{
+			name:           "non-flaky failure",
+			formatExitCode: 1,
+			goTestExitCode: 1,
+			allowFlaky:     true,
+			failedPackages: true,
+			expected:       1,
+		},
+		{name: "unexplained failure", goTestExitCode: 2, allowFlaky: true, expected: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := combineExitCodes(tc.formatExitCode, tc.goTestExitCode, tc.allowFlaky, tc.failedPackages)
+			if actual != tc.expected {
+				t.Fatalf("unexpected exit code: %d (expected: %d)", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// prepareGoTest starts go test in its own process group so signals can be forwarded to the test binaries as well.
+func prepareGoTest(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalGoTest sends the signal to the process group of go test. The go command itself does not pass signals on to the
+// test binaries because it expects the terminal to send them to the whole process group.
+func signalGoTest(cmd *exec.Cmd, sig os.Signal) error {
+	unixSignal, ok := sig.(syscall.Signal)
+	if !ok {
+		return cmd.Process.Signal(sig)
+	}
+	return syscall.Kill(-cmd.Process.Pid, unixSignal)
+}
+
+// processExitCode returns the exit code of the exited process. Processes killed by a signal return 128 plus the signal
+// number, like in a shell.
+func processExitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	if exitCode := state.ExitCode(); exitCode >= 0 {
+		return exitCode
+	}
+	return 1
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+// killHelperProcess kills the helper process with SIGKILL.
+func killHelperProcess() {
+	_ = syscall.Kill(os.Getpid(), syscall.SIGKILL)
+	select {}
+}
+
+// TestStartGoTestSignal checks that a go test killed by a signal results in 128 plus the signal number as exit code.
+func TestStartGoTestSignal(t *testing.T) {
+	_, code := runFakeGoTest(t, "signal")
+	if code != 128+int(syscall.SIGKILL) {
+		t.Fatalf("unexpected exit code: %d (expected: %d)", code, 128+int(syscall.SIGKILL))
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// prepareGoTest does nothing on Windows, go test shares the console with gotestfmt and receives Ctrl+C directly.
+func prepareGoTest(_ *exec.Cmd) {
+}
+
+// signalGoTest sends the signal to go test. Windows does not support sending interrupts to other processes, so this
+// only works for os.Kill.
+func signalGoTest(cmd *exec.Cmd, sig os.Signal) error {
+	return cmd.Process.Signal(sig)
+}
+
+// processExitCode returns the exit code of the exited process.
+func processExitCode(state *os.ProcessState) int {
+	if exitCode := state.ExitCode(); exitCode >= 0 {
+		return exitCode
+	}
+	return 1
+}
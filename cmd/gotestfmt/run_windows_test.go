@@ -0,0 +1,10 @@
+package main
+
+import (
+	"os"
+)
+
+// killHelperProcess ends the helper process. Windows has no signals to kill it with, so it exits with a failure.
+func killHelperProcess() {
+	os.Exit(1)
+}
@@ -16,37 +16,86 @@ import (
 //go:embed .gotestfmt/*/*.gotpl
 var fs embed.FS
 
+// New creates a new formatter using the templates from the specified directories. The optional reporters are called
+// with the complete parse result after the input has been fully processed.
 func New(
 	templateRoot string,
 	templateDirs []string,
-) (CombinedExitCode, error) {
-	downloadsTpl := findTemplate(templateRoot, templateDirs, "downloads.gotpl")
+	reporters ...Reporter,
+) (CombinedError, error) {
+	downloadsTpl, err := findTemplate(templateRoot, templateDirs, "downloads.gotpl")
+	if err != nil {
+		return nil, err
+	}
 
-	packageTpl := findTemplate(templateRoot, templateDirs, "package.gotpl")
+	packageTpl, err := findTemplate(templateRoot, templateDirs, "package.gotpl")
+	if err != nil {
+		return nil, err
+	}
+
+	summaryTpl, err := findTemplate(templateRoot, templateDirs, "summary.gotpl")
+	if err != nil {
+		return nil, err
+	}
 
 	return &goTestFmt{
 		downloadsTpl: downloadsTpl,
 		packageTpl:   packageTpl,
+		summaryTpl:   summaryTpl,
+		reporters:    reporters,
 	}, nil
 }
 
-func findTemplate(root string, dirs []string, tpl string) []byte {
+// NewTemplateReporter creates a reporter that renders the specified template with the complete parse result and
+// appends the output to the target file. The template is looked up in the same directories as the other templates.
+func NewTemplateReporter(
+	templateRoot string,
+	templateDirs []string,
+	templateName string,
+	targetFile string,
+	cfg renderer.RenderSettings,
+) (Reporter, error) {
+	tpl, err := findTemplate(templateRoot, templateDirs, templateName)
+	if err != nil {
+		return nil, err
+	}
+	return ReporterFunc(func(result *parser.ParseResult) error {
+		output, err := renderer.RenderReport(templateName, tpl, result, cfg)
+		if err != nil {
+			return err
+		}
+		fh, err := os.OpenFile(targetFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open %s (%w)", targetFile, err)
+		}
+		if _, err := fh.Write(output); err != nil {
+			_ = fh.Close()
+			return fmt.Errorf("failed to write %s (%w)", targetFile, err)
+		}
+		if err := fh.Close(); err != nil {
+			return fmt.Errorf("failed to close %s (%w)", targetFile, err)
+		}
+		return nil
+	}), nil
+}
+
+func findTemplate(root string, dirs []string, tpl string) ([]byte, error) {
 	var lastError error
 	for _, dir := range dirs {
 		templateContents, err := os.ReadFile(path.Join(root, dir, tpl))
 		if err == nil {
-			return templateContents
+			return templateContents, nil
 		}
 		lastError = err
 	}
 	for _, dir := range dirs {
 		templateContents, err := fs.ReadFile(path.Join("./.gotestfmt", dir, tpl))
 		if err == nil {
-			return templateContents
+			return templateContents, nil
 		}
 		lastError = err
 	}
-	panic(fmt.Errorf("bug: %s not found in binary (%w)", tpl, lastError))
+	return nil, fmt.Errorf("bug: %s not found in binary (%w)", tpl, lastError)
 }
 
 // Combined is an interface that combines both the classic GoTestFmt interface and the Formatter interface.
@@ -63,6 +112,12 @@ type CombinedExitCode interface {
 	FormatterExitCode
 }
 
+// CombinedError contains CombinedExitCode and adds a function to format with exit code and error.
+type CombinedError interface {
+	CombinedExitCode
+	FormatterError
+}
+
 // GoTestFmt implements the classic Format instruction. This is no longer in use.
 //
 // Deprecated: please use the Formatter interface instead.
@@ -82,9 +137,30 @@ type FormatterExitCode interface {
 	FormatWithConfigAndExitCode(input io.Reader, target io.WriteCloser, cfg renderer.RenderSettings) int
 }
 
+// FormatterError contains an extended format function to accept render settings and returns an exit code and the
+// first error that happened while processing the input, rendering the output, or writing the reports.
+type FormatterError interface {
+	FormatWithConfigAndError(input io.Reader, target io.WriteCloser, cfg renderer.RenderSettings) (int, error)
+}
+
+// Reporter receives the complete parse result once all input has been processed, for example to write a report file.
+type Reporter interface {
+	Report(result *parser.ParseResult) error
+}
+
+// ReporterFunc is a function implementing the Reporter interface.
+type ReporterFunc func(result *parser.ParseResult) error
+
+// Report calls the underlying function.
+func (r ReporterFunc) Report(result *parser.ParseResult) error {
+	return r(result)
+}
+
 type goTestFmt struct {
 	packageTpl   []byte
 	downloadsTpl []byte
+	summaryTpl   []byte
+	reporters    []Reporter
 }
 
 func (g *goTestFmt) Format(input io.Reader, target io.WriteCloser) {
@@ -95,25 +171,123 @@ func (g *goTestFmt) FormatWithConfig(input io.Reader, target io.WriteCloser, cfg
 	_ = g.FormatWithConfigAndExitCode(input, target, cfg)
 }
 
+// FormatWithConfigAndExitCode formats the input and returns the exit code. If an error happens the exit code is 1,
+// use FormatWithConfigAndError to receive the error itself.
 func (g *goTestFmt) FormatWithConfigAndExitCode(input io.Reader, target io.WriteCloser, cfg renderer.RenderSettings) int {
-	tokenizerOutput := tokenizer.Tokenize(input)
-	prefixes, downloads, packages := parser.Parse(tokenizerOutput)
-	result, exitCodeChan := renderer.RenderWithSettingsAndExitCode(
-		prefixes,
-		downloads,
-		packages,
-		g.downloadsTpl,
-		g.packageTpl,
-		cfg,
-	)
+	exitCode, err := g.FormatWithConfigAndError(input, target, cfg)
+	if err != nil && exitCode == 0 {
+		return 1
+	}
+	return exitCode
+}
+
+func (g *goTestFmt) FormatWithConfigAndError(
+	input io.Reader,
+	target io.WriteCloser,
+	cfg renderer.RenderSettings,
+) (int, error) {
+	tokenizerOutput, tokenizerErrors := tokenizer.TokenizeWithErrors(input)
+	prefixes, downloads, packages, parserErrors := parser.ParseWithErrors(tokenizerOutput)
+	errorChannels := []<-chan error{tokenizerErrors, parserErrors}
+	if len(cfg.CoverProfiles) > 0 {
+		var coverageErrors <-chan error
+		packages, coverageErrors = parser.ApplyCoverProfiles(packages, cfg.CoverProfiles)
+		errorChannels = append(errorChannels, coverageErrors)
+	}
+	var parseResult *parser.ParseResult
+	var collectorDone <-chan struct{}
+	if len(g.reporters) > 0 {
+		parseResult, collectorDone, prefixes, downloads, packages = collect(prefixes, downloads, packages)
+	}
+	var result <-chan []byte
+	var exitCodeChan <-chan int
+	var renderErrors <-chan error
+	if cfg.OutputFormat == renderer.OutputFormatTAP {
+		result, exitCodeChan, renderErrors = renderer.RenderTAP(prefixes, downloads, packages, cfg)
+	} else {
+		result, exitCodeChan, renderErrors = renderer.RenderWithSettingsAndErrors(
+			prefixes,
+			downloads,
+			packages,
+			g.downloadsTpl,
+			g.packageTpl,
+			g.summaryTpl,
+			cfg,
+		)
+	}
 
+	var firstErr error
 	for {
 		fragment, ok := <-result
 		if !ok {
-			return <-exitCodeChan
+			break
+		}
+		if firstErr != nil {
+			// Keep reading so the pipeline can finish.
+			continue
 		}
 		if _, err := target.Write(fragment); err != nil {
-			panic(fmt.Errorf("failed to write to output: %w", err))
+			firstErr = fmt.Errorf("failed to write to output: %w", err)
 		}
 	}
+	exitCode := <-exitCodeChan
+	if collectorDone != nil {
+		<-collectorDone
+	}
+	for _, errs := range append(errorChannels, renderErrors) {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, reporter := range g.reporters {
+		if err := reporter.Report(parseResult); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	return exitCode, firstErr
+}
+
+// collect passes the parser output through to the returned channels while recording it in a ParseResult. The
+// ParseResult is complete once the returned done channel is closed.
+func collect(
+	prefixes <-chan string,
+	downloads <-chan *parser.Downloads,
+	packages <-chan *parser.Package,
+) (*parser.ParseResult, <-chan struct{}, <-chan string, <-chan *parser.Downloads, <-chan *parser.Package) {
+	parseResult := &parser.ParseResult{}
+	done := make(chan struct{})
+	prefixesOut := make(chan string)
+	downloadsOut := make(chan *parser.Downloads)
+	packagesOut := make(chan *parser.Package)
+	go func() {
+		defer close(done)
+		for {
+			prefix, ok := <-prefixes
+			if !ok {
+				break
+			}
+			parseResult.Prefix = append(parseResult.Prefix, prefix)
+			prefixesOut <- prefix
+		}
+		close(prefixesOut)
+		for {
+			dl, ok := <-downloads
+			if !ok {
+				break
+			}
+			parseResult.Downloads = *dl
+			downloadsOut <- dl
+		}
+		close(downloadsOut)
+		for {
+			pkg, ok := <-packages
+			if !ok {
+				break
+			}
+			parseResult.Packages = append(parseResult.Packages, *pkg)
+			packagesOut <- pkg
+		}
+		close(packagesOut)
+	}()
+	return parseResult, done, prefixesOut, downloadsOut, packagesOut
 }
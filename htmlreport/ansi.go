@@ -0,0 +1,112 @@
+package htmlreport
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ansiRegexp matches ANSI control sequences, such as "\033[0;31m" or the "\033[0K" of GitLab sections.
+var ansiRegexp = regexp.MustCompile("\033\\[([0-9;]*)([A-Za-z])")
+
+var ansiColors = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// ansiStyle is the text style set by the SGR sequences seen so far.
+type ansiStyle struct {
+	bold       bool
+	foreground string
+	background string
+}
+
+// apply changes the style according to the parameters of an SGR sequence, such as "0;31".
+func (s *ansiStyle) apply(parameters string) {
+	codes := strings.Split(parameters, ";")
+	for i := 0; i < len(codes); i++ {
+		code, err := strconv.Atoi(codes[i])
+		if err != nil {
+			// An empty parameter is the same as 0.
+			code = 0
+		}
+		switch {
+		case code == 0:
+			*s = ansiStyle{}
+		case code == 1:
+			s.bold = true
+		case code == 22:
+			s.bold = false
+		case code >= 30 && code <= 37:
+			s.foreground = ansiColors[code-30]
+		case code == 39:
+			s.foreground = ""
+		case code >= 40 && code <= 47:
+			s.background = ansiColors[code-40]
+		case code == 49:
+			s.background = ""
+		case code >= 90 && code <= 97:
+			s.foreground = "bright-" + ansiColors[code-90]
+		case code >= 100 && code <= 107:
+			s.background = "bright-" + ansiColors[code-100]
+		case code == 38 || code == 48:
+			// 256 color and true color sequences are not supported, skip their arguments.
+			if i+1 < len(codes) && codes[i+1] == "5" {
+				i += 2
+			} else if i+1 < len(codes) && codes[i+1] == "2" {
+				i += 4
+			}
+		}
+	}
+}
+
+// classes returns the CSS classes for the style, or an empty string for the default style.
+func (s ansiStyle) classes() string {
+	var classes []string
+	if s.bold {
+		classes = append(classes, "ansi-bold")
+	}
+	if s.foreground != "" {
+		classes = append(classes, "ansi-"+s.foreground)
+	}
+	if s.background != "" {
+		classes = append(classes, "ansi-bg-"+s.background)
+	}
+	return strings.Join(classes, " ")
+}
+
+// ANSIToHTML escapes the text for HTML and converts the ANSI color codes in it into span elements with CSS classes,
+// such as "ansi-red" or "ansi-bold". Other control sequences are removed.
+func ANSIToHTML(text string) template.HTML {
+	result := strings.Builder{}
+	style := ansiStyle{}
+	openClasses := ""
+	write := func(part string) {
+		if part == "" {
+			return
+		}
+		// Spans are only opened once there is text to style, so consecutive sequences do not create empty spans.
+		if classes := style.classes(); classes != openClasses {
+			if openClasses != "" {
+				result.WriteString("</span>")
+			}
+			if classes != "" {
+				result.WriteString(`<span class="` + classes + `">`)
+			}
+			openClasses = classes
+		}
+		result.WriteString(html.EscapeString(part))
+	}
+	last := 0
+	for _, match := range ansiRegexp.FindAllStringSubmatchIndex(text, -1) {
+		write(text[last:match[0]])
+		last = match[1]
+		if text[match[4]:match[5]] == "m" {
+			style.apply(text[match[2]:match[3]])
+		}
+	}
+	write(text[last:])
+	if openClasses != "" {
+		result.WriteString("</span>")
+	}
+	return template.HTML(result.String())
+}
@@ -0,0 +1,58 @@
+(function () {
+    "use strict";
+
+    var filter = document.getElementById("filter");
+    var resultFilters = document.querySelectorAll(".result-filter");
+    var packages = document.querySelectorAll("details.package");
+
+    function selectedResults() {
+        var results = {};
+        for (var i = 0; i < resultFilters.length; i++) {
+            if (resultFilters[i].checked) {
+                results[resultFilters[i].value] = true;
+            }
+        }
+        return results;
+    }
+
+    // applyFilter shows the tests matching the text and result filters, and the packages that either match the filters
+    // themselves or contain a matching test.
+    function applyFilter() {
+        var query = filter.value.toLowerCase();
+        var results = selectedResults();
+        for (var i = 0; i < packages.length; i++) {
+            var pkg = packages[i];
+            var packageMatches = pkg.dataset.name.toLowerCase().indexOf(query) !== -1;
+            var tests = pkg.querySelectorAll("li.test");
+            var visibleTests = 0;
+            for (var j = 0; j < tests.length; j++) {
+                var test = tests[j];
+                var visible = results[test.dataset.result] === true &&
+                    (packageMatches || test.dataset.name.toLowerCase().indexOf(query) !== -1);
+                test.hidden = !visible;
+                if (visible) {
+                    visibleTests++;
+                }
+            }
+            pkg.hidden = !(visibleTests > 0 || (packageMatches && results[pkg.dataset.result] === true));
+        }
+    }
+
+    function setOpen(open) {
+        var details = document.querySelectorAll("main details");
+        for (var i = 0; i < details.length; i++) {
+            details[i].open = open;
+        }
+    }
+
+    filter.addEventListener("input", applyFilter);
+    for (var i = 0; i < resultFilters.length; i++) {
+        resultFilters[i].addEventListener("change", applyFilter);
+    }
+    document.getElementById("expand").addEventListener("click", function () {
+        setOpen(true);
+    });
+    document.getElementById("collapse").addEventListener("click", function () {
+        setOpen(false);
+    });
+})();
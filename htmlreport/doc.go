@@ -0,0 +1,4 @@
+// The htmlreport package converts the results from the parser into a self-contained HTML page that can be viewed
+// without a server.
+
+package htmlreport
@@ -0,0 +1,168 @@
+package htmlreport
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+//go:embed assets/*
+var assets embed.FS
+
+// Report is the data the HTML template is rendered with.
+type Report struct {
+	// Prefix contains the text before any recognized output.
+	Prefix string
+	// Downloads contains the dependency downloads.
+	Downloads parser.Downloads
+	// Packages contains all packages in the order they have been received.
+	Packages []Package
+	// PackageCounts contains the number of packages per result.
+	PackageCounts Counts
+	// TestCounts contains the number of test cases per result, including subtests.
+	TestCounts Counts
+	// Duration is the sum of the durations of all packages.
+	Duration time.Duration
+	// Coverage is the average coverage of the packages that reported coverage, or nil if none did.
+	Coverage *float64
+	// Failed indicates that a download, package or test has failed.
+	Failed bool
+}
+
+// Package is a single package in the report.
+type Package struct {
+	*parser.Package
+
+	// Tests contains the number of test cases in this package per result, including subtests.
+	Tests Counts
+	// DurationPercent is the duration of this package relative to the slowest package.
+	DurationPercent float64
+}
+
+// Counts contains the number of items per result.
+type Counts struct {
+	// Total is the total number of items.
+	Total int
+	// Pass is the number of items that passed.
+	Pass int
+	// Fail is the number of items that failed.
+	Fail int
+	// Skip is the number of items that were skipped.
+	Skip int
+}
+
+func (c *Counts) add(result parser.Result) {
+	c.Total++
+	switch reportResult(result) {
+	case parser.ResultPass:
+		c.Pass++
+	case parser.ResultSkip:
+		c.Skip++
+	default:
+		c.Fail++
+	}
+}
+
+// reportResult returns the result shown in the report. Packages and tests without a result, for example because they
+// never finished, are shown as failed.
+func reportResult(result parser.Result) parser.Result {
+	switch result {
+	case parser.ResultPass, parser.ResultSkip:
+		return result
+	default:
+		return parser.ResultFail
+	}
+}
+
+// Write converts the parse result into an HTML page and writes it to the target. The styles and scripts are included
+// in the page, so it does not load any other files.
+func Write(target io.Writer, result *parser.ParseResult) error {
+	tpl, err := parseTemplate()
+	if err != nil {
+		return err
+	}
+	if err := tpl.Execute(target, Convert(result)); err != nil {
+		return fmt.Errorf("failed to render HTML report (%w)", err)
+	}
+	return nil
+}
+
+// Convert creates the data for the HTML template from a parse result.
+func Convert(result *parser.ParseResult) Report {
+	report := Report{
+		Prefix:    strings.Join(result.Prefix, "\n"),
+		Downloads: result.Downloads,
+		Failed:    result.Downloads.Failed,
+	}
+	var maxDuration time.Duration
+	coverageSum := 0.0
+	coverageCount := 0
+	for i := range result.Packages {
+		pkg := &result.Packages[i]
+		reportPackage := Package{
+			Package: pkg,
+		}
+		for _, tc := range pkg.TestCases {
+			reportPackage.Tests.add(tc.Result)
+			report.TestCounts.add(tc.Result)
+		}
+		report.PackageCounts.add(pkg.Result)
+		report.Duration += pkg.Duration
+		if reportResult(pkg.Result) == parser.ResultFail {
+			report.Failed = true
+		}
+		if pkg.Coverage != nil {
+			coverageSum += *pkg.Coverage
+			coverageCount++
+		}
+		if pkg.Duration > maxDuration {
+			maxDuration = pkg.Duration
+		}
+		report.Packages = append(report.Packages, reportPackage)
+	}
+	if coverageCount > 0 {
+		coverage := math.Round(coverageSum/float64(coverageCount)*10) / 10
+		report.Coverage = &coverage
+	}
+	if maxDuration > 0 {
+		for i := range report.Packages {
+			pkg := &report.Packages[i]
+			pkg.DurationPercent = math.Round(float64(pkg.Duration)*1000/float64(maxDuration)) / 10
+		}
+	}
+	return report
+}
+
+func parseTemplate() (*template.Template, error) {
+	files := map[string]string{}
+	for _, name := range []string{"report.gotpl", "report.css", "report.js"} {
+		data, err := assets.ReadFile("assets/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("bug: %s not found in binary (%w)", name, err)
+		}
+		files[name] = string(data)
+	}
+	tpl := template.New("report.gotpl")
+	tpl.Funcs(map[string]interface{}{
+		"ansi": ANSIToHTML,
+		"css": func() template.CSS {
+			return template.CSS(files["report.css"])
+		},
+		"js": func() template.JS {
+			return template.JS(files["report.js"])
+		},
+		"lower":  strings.ToLower,
+		"result": reportResult,
+	})
+	tpl, err := tpl.Parse(files["report.gotpl"])
+	if err != nil {
+		return nil, fmt.Errorf("bug: failed to parse HTML report template (%w)", err)
+	}
+	return tpl, nil
+}
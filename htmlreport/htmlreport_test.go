@@ -0,0 +1,111 @@
+package htmlreport_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotesttools/gotestfmt/v2/htmlreport"
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// TestWrite writes a report with a passing and a failing package and checks that the names and the escaped output are
+// included and that the page does not reference other files.
+func TestWrite(t *testing.T) {
+	coverage := 75.5
+	result := &parser.ParseResult{
+		Packages: []parser.Package{
+			{
+				Name:     "example.com/pass",
+				Result:   parser.ResultPass,
+				Duration: 500 * time.Millisecond,
+				Coverage: &coverage,
+				TestCases: []*parser.TestCase{
+					{Name: "TestPass", Result: parser.ResultPass, Duration: 250 * time.Millisecond},
+				},
+			},
+			{
+				Name:     "example.com/fail",
+				Result:   parser.ResultFail,
+				Duration: time.Second,
+				TestCases: []*parser.TestCase{
+					{Name: "TestFail", Result: parser.ResultFail, Output: "    fail_test.go:10: <b>boom</b>"},
+				},
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := htmlreport.Write(buf, result); err != nil {
+		t.Fatalf("failed to write report (%v)", err)
+	}
+	output := buf.String()
+	for _, expected := range []string{
+		"<!DOCTYPE html>",
+		`data-name="example.com/pass"`,
+		`data-name="TestFail"`,
+		"fail_test.go:10: &lt;b&gt;boom&lt;/b&gt;",
+		"75.5%",
+		`style="width: 50%"`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("the report does not contain %s:\n%s", expected, output)
+		}
+	}
+	for _, unexpected := range []string{"<b>boom", " src=", " href="} {
+		if strings.Contains(output, unexpected) {
+			t.Fatalf("the report contains %s:\n%s", unexpected, output)
+		}
+	}
+}
+
+// TestWriteUnfinished checks that packages and tests without a result, for example because go test was interrupted,
+// are reported as failed.
+func TestWriteUnfinished(t *testing.T) {
+	result := &parser.ParseResult{
+		Packages: []parser.Package{
+			{
+				Name: "example.com/unfinished",
+				TestCases: []*parser.TestCase{
+					{Name: "TestRunning", Output: "    running_test.go:5: still running"},
+				},
+			},
+		},
+	}
+	report := htmlreport.Convert(result)
+	if !report.Failed || report.PackageCounts.Fail != 1 || report.TestCounts.Fail != 1 {
+		t.Fatalf("the unfinished package and test are not counted as failed: %+v", report)
+	}
+	buf := &bytes.Buffer{}
+	if err := htmlreport.Write(buf, result); err != nil {
+		t.Fatalf("failed to write report (%v)", err)
+	}
+	output := buf.String()
+	for _, expected := range []string{
+		`<details class="package result-fail" data-name="example.com/unfinished" data-result="FAIL" open>`,
+		`<li class="test result-fail" data-name="TestRunning" data-result="FAIL"`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("the report does not contain %s:\n%s", expected, output)
+		}
+	}
+}
+
+// TestANSIToHTML checks the conversion of color codes into span elements.
+func TestANSIToHTML(t *testing.T) {
+	for input, expected := range map[string]string{
+		"plain <text>":                          "plain &lt;text&gt;",
+		"\033[0;31mred\033[0m normal":           `<span class="ansi-red">red</span> normal`,
+		"\033[31mred \033[1mbold\033[22m":       `<span class="ansi-red">red </span><span class="ansi-bold ansi-red">bold</span>`,
+		"\033[1m\033[32mbold green\033[0m":      `<span class="ansi-bold ansi-green">bold green</span>`,
+		"\033[0Ksection\r\033[0K":               "section\r",
+		"\033[38;5;196mextended\033[39m":        "extended",
+		"\033[97;41mbright on red\033[0m":       `<span class="ansi-bright-white ansi-bg-red">bright on red</span>`,
+		"\033[31munterminated":                  `<span class="ansi-red">unterminated</span>`,
+		"\033[33myellow\033[mdefault \033[;34m": `<span class="ansi-yellow">yellow</span>default `,
+	} {
+		if actual := string(htmlreport.ANSIToHTML(input)); actual != expected {
+			t.Fatalf("unexpected conversion of %q: %q (expected: %q)", input, actual, expected)
+		}
+	}
+}
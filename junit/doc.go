@@ -0,0 +1,3 @@
+// The junit package converts the results from the parser into JUnit XML reports that can be consumed by CI systems.
+
+package junit
@@ -0,0 +1,225 @@
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// TestSuites is the root element of a JUnit XML report.
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite contains the results of a single package.
+type TestSuite struct {
+	Name       string      `xml:"name,attr"`
+	ID         int         `xml:"id,attr"`
+	Tests      int         `xml:"tests,attr"`
+	Failures   int         `xml:"failures,attr"`
+	Errors     int         `xml:"errors,attr"`
+	Skipped    int         `xml:"skipped,attr"`
+	Time       string      `xml:"time,attr"`
+	Timestamp  string      `xml:"timestamp,attr,omitempty"`
+	Properties *Properties `xml:"properties,omitempty"`
+	TestCases  []TestCase  `xml:"testcase"`
+	SystemOut  string      `xml:"system-out,omitempty"`
+}
+
+// Properties is the list of properties attached to a test suite.
+type Properties struct {
+	Property []Property `xml:"property"`
+}
+
+// Property is a key-value pair attached to a test suite.
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// TestCase contains the result of a single test case.
+type TestCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Time      string   `xml:"time,attr"`
+	Skipped   *Message `xml:"skipped,omitempty"`
+	Failure   *Message `xml:"failure,omitempty"`
+	Error     *Message `xml:"error,omitempty"`
+	SystemOut string   `xml:"system-out,omitempty"`
+}
+
+// Message is the description of a skip, failure or error.
+type Message struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// Write converts the parse result into a JUnit XML document and writes it to the target.
+func Write(target io.Writer, result *parser.ParseResult) error {
+	suites := Convert(result)
+	if _, err := io.WriteString(target, xml.Header); err != nil {
+		return fmt.Errorf("failed to write JUnit XML header (%w)", err)
+	}
+	encoder := xml.NewEncoder(target)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return fmt.Errorf("failed to encode JUnit XML (%w)", err)
+	}
+	if _, err := io.WriteString(target, "\n"); err != nil {
+		return fmt.Errorf("failed to write JUnit XML (%w)", err)
+	}
+	return nil
+}
+
+// Convert creates the JUnit XML structure from a parse result. Failed dependency downloads are reported as errored
+// test suites.
+func Convert(result *parser.ParseResult) TestSuites {
+	suites := TestSuites{}
+	var totalDuration time.Duration
+	for _, dl := range result.Downloads.Packages {
+		if !dl.Failed {
+			continue
+		}
+		suites.Suites = append(suites.Suites, downloadSuite(dl.Package, dl.Version, dl.Reason))
+	}
+	if result.Downloads.Reason != "" {
+		suites.Suites = append(suites.Suites, downloadSuite("dependency downloads", "", result.Downloads.Reason))
+	}
+	for i := range result.Packages {
+		pkg := &result.Packages[i]
+		suites.Suites = append(suites.Suites, packageSuite(pkg))
+		totalDuration += pkg.Duration
+	}
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		suite.ID = i
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+	}
+	suites.Time = formatDuration(totalDuration)
+	return suites
+}
+
+func downloadSuite(name string, version string, reason string) TestSuite {
+	title := "download"
+	if version != "" {
+		title = fmt.Sprintf("download %s", version)
+	}
+	return TestSuite{
+		Name:   name,
+		Tests:  1,
+		Errors: 1,
+		Time:   formatDuration(0),
+		TestCases: []TestCase{
+			{
+				Name:      title,
+				ClassName: name,
+				Time:      formatDuration(0),
+				Error: &Message{
+					Message: "Dependency download failed",
+					Text:    reason,
+				},
+			},
+		},
+	}
+}
+
+func packageSuite(pkg *parser.Package) TestSuite {
+	suite := TestSuite{
+		Name:      pkg.Name,
+		Time:      formatDuration(pkg.Duration),
+		SystemOut: withRaces(pkg.Output, pkg.Races),
+	}
+	if pkg.StartTime != nil {
+		suite.Timestamp = pkg.StartTime.UTC().Format("2006-01-02T15:04:05")
+	}
+	if pkg.Coverage != nil {
+		suite.Properties = &Properties{
+			Property: []Property{
+				{
+					Name:  "coverage.statements.pct",
+					Value: fmt.Sprintf("%.2f", *pkg.Coverage),
+				},
+			},
+		}
+	}
+	failedTests := 0
+	for _, tc := range pkg.TestCases {
+		output := withRaces(tc.Output, tc.Races)
+		testCase := TestCase{
+			Name:      tc.Name,
+			ClassName: pkg.Name,
+			Time:      formatDuration(tc.Duration),
+			SystemOut: output,
+		}
+		switch tc.Result {
+		case parser.ResultSkip:
+			testCase.Skipped = &Message{
+				Message: skipMessage(tc.Output),
+			}
+			suite.Skipped++
+		case parser.ResultFail:
+			testCase.Failure = &Message{
+				Message: "Failed",
+				Text:    output,
+			}
+			suite.Failures++
+			failedTests++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	if pkg.Result == parser.ResultFail && failedTests == 0 {
+		// The package failed without a failing test, e.g. because of a build error.
+		message := pkg.Reason
+		if message == "" {
+			message = "Package failed"
+		}
+		suite.TestCases = append(suite.TestCases, TestCase{
+			Name:      fmt.Sprintf("[%s]", message),
+			ClassName: pkg.Name,
+			Time:      formatDuration(0),
+			Error: &Message{
+				Message: message,
+				Text:    withRaces(pkg.Output, pkg.Races),
+			},
+		})
+		suite.SystemOut = ""
+		suite.Errors++
+	}
+	suite.Tests = len(suite.TestCases)
+	return suite
+}
+
+// withRaces appends the race reports the parser removed from the output.
+func withRaces(output string, races []parser.DataRace) string {
+	for _, race := range races {
+		if output != "" {
+			output += "\n"
+		}
+		output += race.Output
+	}
+	return output
+}
+
+// skipMessage returns the last non-empty line of the test output, which typically contains the t.Skip() reason.
+func skipMessage(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func formatDuration(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
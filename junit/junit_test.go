@@ -0,0 +1,97 @@
+package junit_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/gotesttools/gotestfmt/v2/junit"
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// TestWrite writes a report for a parse result with passing, failing and skipped tests, a build failure and a failed
+// download, then decodes the XML and checks the counters and messages.
+func TestWrite(t *testing.T) {
+	coverage := 75.5
+	result := &parser.ParseResult{
+		Downloads: parser.Downloads{
+			Packages: []*parser.Download{
+				{Package: "example.com/ok", Version: "v1.0.0"},
+				{Package: "example.com/missing", Version: "v1.2.3", Failed: true, Reason: "not found"},
+			},
+			Failed: true,
+		},
+		Packages: []parser.Package{
+			{
+				Name:     "example.com/tests",
+				Result:   parser.ResultFail,
+				Duration: 1500 * time.Millisecond,
+				Coverage: &coverage,
+				TestCases: []*parser.TestCase{
+					{Name: "TestPass", Result: parser.ResultPass, Duration: 250 * time.Millisecond},
+					{Name: "TestFail", Result: parser.ResultFail, Output: "    fail_test.go:10: boom"},
+					{Name: "TestSkip", Result: parser.ResultSkip, Output: "    skip_test.go:5: not today"},
+				},
+			},
+			{
+				Name:   "example.com/broken",
+				Result: parser.ResultFail,
+				Reason: "build failed",
+				Output: "broken.go:3:1: syntax error",
+			},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := junit.Write(buf, result); err != nil {
+		t.Fatalf("failed to write report (%v)", err)
+	}
+	var report junit.TestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode report (%v)", err)
+	}
+
+	if len(report.Suites) != 3 {
+		t.Fatalf("expected 3 test suites, got %d", len(report.Suites))
+	}
+	if report.Tests != 5 || report.Failures != 1 || report.Errors != 2 || report.Skipped != 1 {
+		t.Fatalf(
+			"incorrect totals: tests=%d failures=%d errors=%d skipped=%d",
+			report.Tests,
+			report.Failures,
+			report.Errors,
+			report.Skipped,
+		)
+	}
+
+	download := report.Suites[0]
+	if download.Name != "example.com/missing" || download.TestCases[0].Error == nil {
+		t.Fatalf("the failed download was not reported as an errored suite: %v", download)
+	}
+
+	tests := report.Suites[1]
+	if tests.Time != "1.500" {
+		t.Fatalf("incorrect suite time: %s", tests.Time)
+	}
+	if tests.Properties == nil || tests.Properties.Property[0].Value != "75.50" {
+		t.Fatalf("coverage property missing")
+	}
+	if tests.TestCases[0].Time != "0.250" || tests.TestCases[0].Failure != nil {
+		t.Fatalf("incorrect passing test case: %v", tests.TestCases[0])
+	}
+	if tests.TestCases[1].Failure == nil || tests.TestCases[1].Failure.Text != "    fail_test.go:10: boom" {
+		t.Fatalf("incorrect failing test case: %v", tests.TestCases[1])
+	}
+	if tests.TestCases[2].Skipped == nil || tests.TestCases[2].Skipped.Message != "skip_test.go:5: not today" {
+		t.Fatalf("incorrect skipped test case: %v", tests.TestCases[2])
+	}
+
+	broken := report.Suites[2]
+	if len(broken.TestCases) != 1 || broken.TestCases[0].Error == nil {
+		t.Fatalf("the build failure was not reported as an error: %v", broken)
+	}
+	if broken.TestCases[0].Error.Message != "build failed" {
+		t.Fatalf("incorrect build failure message: %s", broken.TestCases[0].Error.Message)
+	}
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// testifyFieldRegexp matches the first line of a field in a failed testify assertion, such as
+// "        	Error:      	Not equal: ".
+var testifyFieldRegexp = regexp.MustCompile(`^\s*\t(?P<Label>Error Trace|Error|Test|Messages):\s*\t(?P<Value>.*)$`)
+
+// parseAssertions returns the failed testify assertions in the output of a test case in the given package. Each
+// assertion starts with its "Error Trace:" field.
+func (r *locationResolver) parseAssertions(pkg string, output string) []Assertion {
+	var assertions []Assertion
+	var current *Assertion
+	label := ""
+	var values []string
+	finishField := func() {
+		if current != nil {
+			setAssertionField(current, label, values)
+		}
+		label = ""
+		values = nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if match := testifyFieldRegexp.FindStringSubmatch(line); len(match) != 0 {
+			finishField()
+			if match[1] == "Error Trace" {
+				assertions = append(assertions, Assertion{})
+				current = &assertions[len(assertions)-1]
+				if location := testifyLocationRegexp.FindStringSubmatch(line); len(location) != 0 {
+					if loc, err := r.newLocation(pkg, location[1], location[2]); err == nil {
+						current.Location = loc
+					}
+				}
+			}
+			label = match[1]
+			values = []string{match[2]}
+			continue
+		}
+		if label != "" {
+			if match := testifyContinuationRegexp.FindStringSubmatch(line); len(match) != 0 {
+				values = append(values, match[1])
+				continue
+			}
+		}
+		finishField()
+	}
+	finishField()
+	return assertions
+}
+
+// setAssertionField stores the value of a testify field in the assertion. The error field is split into the message,
+// the expected and actual values, and the diff.
+func setAssertionField(assertion *Assertion, label string, values []string) {
+	switch label {
+	case "Error":
+		var message, expected, actual, diff []string
+		target := &message
+		for _, value := range values {
+			switch {
+			case target == &message && strings.HasPrefix(value, "expected: "):
+				target = &expected
+				value = strings.TrimPrefix(value, "expected: ")
+			case target == &expected && strings.HasPrefix(value, "actual  : "):
+				target = &actual
+				value = strings.TrimPrefix(value, "actual  : ")
+			case target != &diff && value == "Diff:":
+				target = &diff
+				continue
+			}
+			*target = append(*target, value)
+		}
+		assertion.Message = strings.TrimSpace(strings.Join(message, "\n"))
+		assertion.Expected = strings.TrimRight(strings.Join(expected, "\n"), " \n")
+		assertion.Actual = strings.TrimRight(strings.Join(actual, "\n"), " \n")
+		assertion.Diff = strings.Trim(strings.Join(diff, "\n"), "\n")
+	case "Messages":
+		assertion.Messages = strings.TrimSpace(strings.Join(values, "\n"))
+	}
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseBenchmarkResult parses a benchmark result line, such as
+// "BenchmarkFoo-8  1000  1234 ns/op  56 B/op  2 allocs/op". The test name reported alongside the line is used to tell
+// the GOMAXPROCS suffix apart from the benchmark name. If it is empty, a numeric suffix is assumed to be the
+// GOMAXPROCS value. It returns the benchmark name without the suffix.
+func parseBenchmarkResult(test string, line string) (string, BenchmarkResult, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 {
+		return "", BenchmarkResult{}, fmt.Errorf("invalid benchmark result line: %s", line)
+	}
+	result := BenchmarkResult{}
+	name := fields[0]
+	if test == "" || name != test {
+		if i := strings.LastIndex(name, "-"); i > 0 && (test == "" || name[:i] == test) {
+			if procs, err := strconv.Atoi(name[i+1:]); err == nil {
+				name = name[:i]
+				result.Procs = procs
+			}
+		}
+	}
+	iterations, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", BenchmarkResult{}, fmt.Errorf("invalid benchmark iterations: %s (%w)", fields[1], err)
+	}
+	result.Iterations = iterations
+	for i := 2; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return "", BenchmarkResult{}, fmt.Errorf("invalid benchmark value: %s (%w)", fields[i], err)
+		}
+		switch unit := fields[i+1]; unit {
+		case "ns/op":
+			result.NsPerOp = value
+		case "B/op":
+			bytesPerOp := int64(value)
+			result.BytesPerOp = &bytesPerOp
+		case "allocs/op":
+			allocsPerOp := int64(value)
+			result.AllocsPerOp = &allocsPerOp
+		default:
+			if result.Metrics == nil {
+				result.Metrics = map[string]float64{}
+			}
+			result.Metrics[unit] = value
+		}
+	}
+	return name, result, nil
+}
@@ -0,0 +1,316 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Coverage modes of go test -covermode.
+const (
+	CoverModeSet    = "set"
+	CoverModeCount  = "count"
+	CoverModeAtomic = "atomic"
+)
+
+var coverModeRegexp = regexp.MustCompile(`^mode: (?P<Mode>set|count|atomic)$`)
+
+// coverBlockRegexp matches a block line of a coverage profile, such as "example.com/foo/foo.go:3.24,5.2 1 1".
+var coverBlockRegexp = regexp.MustCompile(
+	`^(?P<File>.+):(?P<StartLine>[0-9]+)\.(?P<StartCol>[0-9]+),(?P<EndLine>[0-9]+)\.(?P<EndCol>[0-9]+) (?P<Statements>[0-9]+) (?P<Count>[0-9]+)$`,
+)
+
+// CoverProfile is a coverage profile as written by go test -coverprofile. Profiles of separate test runs can be
+// combined with Merge.
+type CoverProfile struct {
+	// Mode is the coverage mode of the profile, one of CoverModeSet, CoverModeCount, and CoverModeAtomic.
+	Mode string
+	// blocks holds the execution count of the code blocks by file name.
+	blocks map[string]map[coverBlock]int64
+}
+
+// coverBlock is a range of statements in a source file.
+type coverBlock struct {
+	startLine  int
+	startCol   int
+	endLine    int
+	endCol     int
+	statements int
+}
+
+// ParseCoverProfile reads a coverage profile. Blocks listed more than once, for example when the profiles of several
+// packages are concatenated, are merged.
+func ParseCoverProfile(input io.Reader) (*CoverProfile, error) {
+	profile := &CoverProfile{
+		blocks: map[string]map[coverBlock]int64{},
+	}
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if match := coverModeRegexp.FindStringSubmatch(line); len(match) != 0 {
+			if profile.Mode != "" && profile.Mode != match[1] {
+				return nil, fmt.Errorf(
+					"conflicting coverage modes %s and %s on line %d",
+					profile.Mode,
+					match[1],
+					lineNumber,
+				)
+			}
+			profile.Mode = match[1]
+			continue
+		}
+		if profile.Mode == "" {
+			return nil, fmt.Errorf("missing mode line at the start of the coverage profile")
+		}
+		match := coverBlockRegexp.FindStringSubmatch(line)
+		if len(match) == 0 {
+			return nil, fmt.Errorf("invalid line %d in coverage profile: %s", lineNumber, line)
+		}
+		numbers := make([]int64, 6)
+		for i := range numbers {
+			n, err := strconv.ParseInt(match[i+2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid line %d in coverage profile: %s (%w)", lineNumber, line, err)
+			}
+			numbers[i] = n
+		}
+		profile.add(match[1], coverBlock{
+			startLine:  int(numbers[0]),
+			startCol:   int(numbers[1]),
+			endLine:    int(numbers[2]),
+			endCol:     int(numbers[3]),
+			statements: int(numbers[4]),
+		}, numbers[5])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read coverage profile (%w)", err)
+	}
+	return profile, nil
+}
+
+// Merge adds the execution counts of the other profile to this profile. Both profiles must have the same mode.
+func (p *CoverProfile) Merge(other *CoverProfile) error {
+	if other == nil {
+		return nil
+	}
+	if p.Mode == "" {
+		p.Mode = other.Mode
+	} else if other.Mode != "" && p.Mode != other.Mode {
+		return fmt.Errorf("cannot merge coverage profiles with the modes %s and %s", p.Mode, other.Mode)
+	}
+	if p.blocks == nil {
+		p.blocks = map[string]map[coverBlock]int64{}
+	}
+	for file, blocks := range other.blocks {
+		for block, count := range blocks {
+			p.add(file, block, count)
+		}
+	}
+	return nil
+}
+
+func (p *CoverProfile) add(file string, block coverBlock, count int64) {
+	blocks, ok := p.blocks[file]
+	if !ok {
+		blocks = map[coverBlock]int64{}
+		p.blocks[file] = blocks
+	}
+	if previous, ok := blocks[block]; ok && p.Mode == CoverModeSet {
+		if count > previous {
+			blocks[block] = count
+		}
+		return
+	}
+	blocks[block] += count
+}
+
+// Files returns the names of the source files in the profile, such as "example.com/foo/foo.go", sorted by name.
+func (p *CoverProfile) Files() []string {
+	files := make([]string, 0, len(p.blocks))
+	for file := range p.blocks {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Apply sets the CoverageByFile field of the package to the coverage of the files in the package directory. The
+// function coverage is only filled in if the source files can be found in the current module.
+func (p *CoverProfile) Apply(pkg *Package) {
+	p.apply(newLocationResolver(), pkg)
+}
+
+func (p *CoverProfile) apply(resolver *locationResolver, pkg *Package) {
+	var files []FileCoverage
+	for _, file := range p.Files() {
+		if path.Dir(file) != pkg.Name {
+			continue
+		}
+		files = append(files, resolver.fileCoverage(file, p.blocks[file]))
+	}
+	pkg.CoverageByFile = files
+}
+
+// fileCoverage calculates the coverage of a single file from its blocks.
+func (r *locationResolver) fileCoverage(file string, blocks map[coverBlock]int64) FileCoverage {
+	result := FileCoverage{
+		File: file,
+	}
+	for block, count := range blocks {
+		result.Statements += block.statements
+		if count > 0 {
+			result.CoveredStatements += block.statements
+		}
+	}
+	source := r.sourceFile(file)
+	if source == "" {
+		return result
+	}
+	result.File = r.relativeToRepo(file, source)
+	result.Functions = functionCoverage(source, blocks)
+	return result
+}
+
+// sourceFile returns the absolute path of a file in the coverage profile, or an empty string if the file does not
+// exist. File names are import paths, except for packages outside a module.
+func (r *locationResolver) sourceFile(file string) string {
+	absolute := file
+	if !filepath.IsAbs(file) {
+		if r.modulePath == "" || !strings.HasPrefix(file, r.modulePath+"/") {
+			return ""
+		}
+		absolute = filepath.Join(r.moduleRoot, filepath.FromSlash(strings.TrimPrefix(file, r.modulePath+"/")))
+	}
+	if _, err := os.Stat(absolute); err != nil {
+		return ""
+	}
+	return absolute
+}
+
+// functionCoverage returns the coverage of the functions declared in the source file. A block belongs to a function
+// if it lies within the function declaration, the same way go tool cover -func assigns them.
+func functionCoverage(source string, blocks map[coverBlock]int64) []FunctionCoverage {
+	fileSet := token.NewFileSet()
+	parsed, err := goparser.ParseFile(fileSet, source, nil, 0)
+	if err != nil {
+		return nil
+	}
+	var functions []FunctionCoverage
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		start := fileSet.Position(fn.Pos())
+		end := fileSet.Position(fn.End())
+		function := FunctionCoverage{
+			Name: functionName(fn),
+			Line: start.Line,
+		}
+		for block, count := range blocks {
+			if !positionBefore(start.Line, start.Column, block.startLine, block.startCol) ||
+				!positionBefore(block.endLine, block.endCol, end.Line, end.Column) {
+				continue
+			}
+			function.Statements += block.statements
+			if count > 0 {
+				function.CoveredStatements += block.statements
+			}
+		}
+		functions = append(functions, function)
+	}
+	return functions
+}
+
+// functionName returns the name of a function declaration, prefixed with the receiver type for methods, such as
+// "Foo.Bar".
+func functionName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	for {
+		switch t := recv.(type) {
+		case *ast.StarExpr:
+			recv = t.X
+			continue
+		case *ast.IndexExpr:
+			recv = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + fn.Name.Name
+		}
+		return fn.Name.Name
+	}
+}
+
+func positionBefore(line1 int, col1 int, line2 int, col2 int) bool {
+	return line1 < line2 || (line1 == line2 && col1 <= col2)
+}
+
+// ApplyCoverProfiles reads and merges the coverage profiles once the input channel is closed and attaches the
+// coverage to each package before passing it on. go test only writes the profile after all packages have finished, so
+// the packages are held back until then.
+func ApplyCoverProfiles(packages <-chan *Package, files []string) (<-chan *Package, <-chan error) {
+	output := make(chan *Package)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(output)
+		var buffered []*Package
+		for {
+			pkg, ok := <-packages
+			if !ok {
+				break
+			}
+			buffered = append(buffered, pkg)
+		}
+		profile, err := readCoverProfiles(files)
+		if err != nil {
+			errs <- err
+		}
+		resolver := newLocationResolver()
+		for _, pkg := range buffered {
+			if profile != nil {
+				profile.apply(resolver, pkg)
+			}
+			output <- pkg
+		}
+	}()
+	return output, errs
+}
+
+func readCoverProfiles(files []string) (*CoverProfile, error) {
+	merged := &CoverProfile{}
+	for _, file := range files {
+		fh, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open coverage profile %s (%w)", file, err)
+		}
+		profile, err := ParseCoverProfile(fh)
+		_ = fh.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse coverage profile %s (%w)", file, err)
+		}
+		if err := merged.Merge(profile); err != nil {
+			return nil, fmt.Errorf("failed to merge coverage profile %s (%w)", file, err)
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,88 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// TestCoverProfile parses two count mode profiles of separate packages covering the same code, merges them, and checks
+// the per-file coverage attached to the package.
+func TestCoverProfile(t *testing.T) {
+	first, err := parser.ParseCoverProfile(strings.NewReader(`mode: count
+example.com/foo/a.go:3.20,5.2 2 1
+example.com/foo/a.go:7.20,9.2 2 0
+example.com/foo/b.go:3.20,6.2 3 0
+example.com/bar/c.go:3.20,5.2 1 4
+`))
+	if err != nil {
+		t.Fatalf("failed to parse first profile (%v)", err)
+	}
+	second, err := parser.ParseCoverProfile(strings.NewReader(`mode: count
+example.com/foo/a.go:3.20,5.2 2 0
+example.com/foo/a.go:7.20,9.2 2 2
+example.com/foo/b.go:3.20,6.2 3 0
+example.com/foo/d.go:3.20,4.2 1 1
+`))
+	if err != nil {
+		t.Fatalf("failed to parse second profile (%v)", err)
+	}
+	if err := first.Merge(second); err != nil {
+		t.Fatalf("failed to merge profiles (%v)", err)
+	}
+
+	pkg := &parser.Package{Name: "example.com/foo"}
+	first.Apply(pkg)
+	expected := []parser.FileCoverage{
+		{File: "example.com/foo/a.go", Statements: 4, CoveredStatements: 4},
+		{File: "example.com/foo/b.go", Statements: 3, CoveredStatements: 0},
+		{File: "example.com/foo/d.go", Statements: 1, CoveredStatements: 1},
+	}
+	if len(pkg.CoverageByFile) != len(expected) {
+		t.Fatalf("unexpected coverage: %v", pkg.CoverageByFile)
+	}
+	for i, file := range expected {
+		actual := pkg.CoverageByFile[i]
+		if actual.File != file.File ||
+			actual.Statements != file.Statements ||
+			actual.CoveredStatements != file.CoveredStatements {
+			t.Fatalf("unexpected coverage for file %d: %v (expected: %v)", i, actual, file)
+		}
+	}
+
+	least := pkg.LeastCoveredFiles(2)
+	if len(least) != 2 || least[0].File != "example.com/foo/b.go" || least[0].Percent() != 0 {
+		t.Fatalf("unexpected least covered files: %v", least)
+	}
+}
+
+// TestCoverProfileModes checks that set mode profiles are merged without counting blocks twice and that profiles with
+// different modes cannot be merged.
+func TestCoverProfileModes(t *testing.T) {
+	set, err := parser.ParseCoverProfile(strings.NewReader(`mode: set
+example.com/foo/a.go:3.20,5.2 3 1
+example.com/foo/a.go:3.20,5.2 3 1
+example.com/foo/a.go:7.20,9.2 1 0
+`))
+	if err != nil {
+		t.Fatalf("failed to parse profile (%v)", err)
+	}
+	pkg := &parser.Package{Name: "example.com/foo"}
+	set.Apply(pkg)
+	if len(pkg.CoverageByFile) != 1 || pkg.CoverageByFile[0].Percent() != 75 {
+		t.Fatalf("unexpected coverage: %v", pkg.CoverageByFile)
+	}
+
+	atomic, err := parser.ParseCoverProfile(strings.NewReader("mode: atomic\nexample.com/foo/a.go:3.20,5.2 3 5\n"))
+	if err != nil {
+		t.Fatalf("failed to parse profile (%v)", err)
+	}
+	if err := set.Merge(atomic); err == nil {
+		t.Fatalf("merging profiles with different modes did not fail")
+	}
+
+	if _, err := parser.ParseCoverProfile(strings.NewReader("example.com/foo/a.go:3.20,5.2 3 5\n")); err == nil {
+		t.Fatalf("parsing a profile without a mode line did not fail")
+	}
+}
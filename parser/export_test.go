@@ -0,0 +1,13 @@
+package parser
+
+// SetWorkDir makes the parser resolve source locations as if it was started in the given directory. The returned
+// function restores the previous behavior.
+func SetWorkDir(dir string) func() {
+	previous := getwd
+	getwd = func() (string, error) {
+		return dir, nil
+	}
+	return func() {
+		getwd = previous
+	}
+}
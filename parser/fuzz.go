@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fuzzProgressRegexp matches a progress line printed while fuzzing, such as
+// "fuzz: elapsed: 3s, execs: 1234 (411/sec), new interesting: 2 (total: 12)".
+var fuzzProgressRegexp = regexp.MustCompile(`^fuzz: elapsed: (?P<Elapsed>[^\s,]+), (?P<Status>.*)$`)
+
+// fuzzExecsRegexp matches the status of a progress line once the fuzzing has started.
+var fuzzExecsRegexp = regexp.MustCompile(
+	`^execs: (?P<Execs>[0-9]+) \((?P<Rate>[0-9]+)/sec\), new interesting: (?P<New>[0-9]+) \(total: (?P<Total>[0-9]+)\)$`,
+)
+
+// fuzzWorkersRegexp matches the end of the progress line printed once the baseline coverage has been gathered.
+var fuzzWorkersRegexp = regexp.MustCompile(`now fuzzing with (?P<Workers>[0-9]+) workers?$`)
+
+// fuzzFailingInputRegexp matches the line announcing the corpus file of the input that made the fuzz test fail.
+var fuzzFailingInputRegexp = regexp.MustCompile(`^\s*Failing input written to (?P<File>[^\s]+)$`)
+
+// parseFuzzProgress updates the fuzzing statistics from a progress line. Lines without statistics, such as the ones
+// printed while minimizing a failing input, are ignored.
+func parseFuzzProgress(fuzz *FuzzSummary, line string) error {
+	match := fuzzProgressRegexp.FindStringSubmatch(line)
+	if len(match) == 0 {
+		return nil
+	}
+	elapsed, err := time.ParseDuration(match[1])
+	if err != nil {
+		return fmt.Errorf("invalid fuzzing time: %s (%w)", match[1], err)
+	}
+	fuzz.Elapsed = elapsed
+	if workers := fuzzWorkersRegexp.FindStringSubmatch(match[2]); len(workers) != 0 {
+		if fuzz.Workers, err = strconv.Atoi(workers[1]); err != nil {
+			return fmt.Errorf("invalid number of fuzzing workers: %s (%w)", workers[1], err)
+		}
+	}
+	execs := fuzzExecsRegexp.FindStringSubmatch(match[2])
+	if len(execs) == 0 {
+		return nil
+	}
+	values := make([]int64, 0, 4)
+	for _, value := range execs[1:] {
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid fuzzing statistics: %s (%w)", match[2], err)
+		}
+		values = append(values, number)
+	}
+	fuzz.Execs = values[0]
+	fuzz.ExecsPerSecond = values[1]
+	fuzz.NewInteresting = values[2]
+	fuzz.TotalInteresting = values[3]
+	return nil
+}
+
+// failingInput returns the corpus file that made the fuzz test fail. With -fuzz the file is printed in the output.
+// Without it, the files in testdata/fuzz are run as subtests named after the file, the seed corpus entries added with
+// f.Add are named "seed#N".
+func failingInput(tc *TestCase) string {
+	if tc.Result != ResultFail {
+		return ""
+	}
+	for _, line := range strings.Split(tc.Output, "\n") {
+		if match := fuzzFailingInputRegexp.FindStringSubmatch(line); len(match) != 0 {
+			return match[1]
+		}
+	}
+	if tc.Parent == nil || tc.Parent.Parent != nil || !strings.HasPrefix(tc.Parent.Name, "Fuzz") ||
+		strings.HasPrefix(tc.ShortName(), "seed#") {
+		return ""
+	}
+	return "testdata/fuzz/" + tc.Name
+}
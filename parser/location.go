@@ -0,0 +1,284 @@
+package parser
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// locationRegexp matches the source locations printed by the testing package, such as "    foo_test.go:12: message".
+var locationRegexp = regexp.MustCompile(
+	`^\s*(?P<File>(?:[A-Za-z]:)?[^\s:]+\.go):(?P<Line>[0-9]+)(?::[0-9]+)?:(?:\s(?P<Message>.*))?$`,
+)
+
+// testifyLocationRegexp matches the location of a failed testify assertion, such as
+// "Error Trace:	/path/to/foo_test.go:12".
+var testifyLocationRegexp = regexp.MustCompile(
+	`^\s*Error Trace:\s*(?P<File>(?:[A-Za-z]:)?[^\s:]+\.go):(?P<Line>[0-9]+)\s*$`,
+)
+
+// testifyMessageRegexp matches the error message of a failed testify assertion.
+var testifyMessageRegexp = regexp.MustCompile(`^\s*Error:\s*(?P<Message>.*)$`)
+
+// testifyContinuationRegexp matches the lines continuing the previous testify field, such as the expected and actual
+// values following the error message.
+var testifyContinuationRegexp = regexp.MustCompile(`^\s*\t\s+\t(?P<Message>.*)$`)
+
+var moduleRegexp = regexp.MustCompile(`(?m)^module\s+"?(?P<Module>[^"\s]+)"?\s*$`)
+
+// getwd returns the working directory the source locations are resolved from. Tests replace it to get the same results
+// regardless of where the repository is checked out.
+var getwd = os.Getwd
+
+// locationResolver extracts source locations from the test output and converts the file names into paths relative to
+// the repository root.
+type locationResolver struct {
+	// repoRoot is the directory the paths are made relative to.
+	repoRoot string
+	// moduleRoot is the directory containing the go.mod file of the module under test.
+	moduleRoot string
+	// modulePath is the module name from the go.mod file.
+	modulePath string
+	// workDir is the working directory, which the file names in the build output are relative to.
+	workDir string
+}
+
+// newLocationResolver creates a location resolver based on the current working directory. The repository root is the
+// closest parent directory containing a .git entry, falling back to the module root or the working directory.
+func newLocationResolver() *locationResolver {
+	wd, err := getwd()
+	if err != nil {
+		return &locationResolver{}
+	}
+	resolver := &locationResolver{
+		repoRoot: findParentWith(wd, ".git"),
+		workDir:  wd,
+	}
+	resolver.moduleRoot, resolver.modulePath = findModule(wd)
+	if resolver.repoRoot == "" {
+		resolver.repoRoot = resolver.moduleRoot
+	}
+	if resolver.repoRoot == "" {
+		resolver.repoRoot = wd
+	}
+	return resolver
+}
+
+// ModulePath returns the module path from the go.mod file in the given directory or the closest parent directory
+// containing one. It returns an empty string if there is no go.mod file.
+func ModulePath(dir string) string {
+	_, modulePath := findModule(dir)
+	return modulePath
+}
+
+// findModule returns the directory containing the go.mod file for the given directory and the module path from it.
+func findModule(dir string) (string, string) {
+	moduleRoot := findParentWith(dir, "go.mod")
+	if moduleRoot == "" {
+		return "", ""
+	}
+	data, err := os.ReadFile(filepath.Join(moduleRoot, "go.mod"))
+	if err != nil {
+		return "", ""
+	}
+	match := moduleRegexp.FindSubmatch(data)
+	if len(match) == 0 {
+		return "", ""
+	}
+	return moduleRoot, string(match[1])
+}
+
+func findParentWith(dir string, name string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// extract returns the locations of the failures in the output of a test case in the given package. If the output
+// contains failed testify assertions their locations are returned. Otherwise, every location in the output is returned
+// in the order of appearance, because t.Log and t.Error output cannot be told apart. Locations are only listed once per
+// file and line.
+func (r *locationResolver) extract(pkg string, output string) []Location {
+	lines := strings.Split(output, "\n")
+	var locations []Location
+	seen := map[Location]bool{}
+	for i, line := range lines {
+		match := testifyLocationRegexp.FindStringSubmatch(line)
+		if len(match) == 0 {
+			continue
+		}
+		location, err := r.newLocation(pkg, match[1], match[2])
+		if err != nil || seen[location] {
+			continue
+		}
+		seen[location] = true
+		location.Message = testifyMessage(lines[i+1:])
+		locations = append(locations, location)
+	}
+	if len(locations) > 0 {
+		return locations
+	}
+	for i, line := range lines {
+		match := locationRegexp.FindStringSubmatch(line)
+		if len(match) == 0 {
+			continue
+		}
+		location, err := r.newLocation(pkg, match[1], match[2])
+		if err != nil || seen[location] {
+			continue
+		}
+		seen[location] = true
+		location.Message = strings.TrimSpace(strings.Join(
+			append([]string{match[3]}, messageContinuation(line, lines[i+1:])...),
+			"\n",
+		))
+		locations = append(locations, location)
+	}
+	return locations
+}
+
+// messageContinuation returns the lines continuing a multi-line message, which the testing package indents further
+// than the line with the location.
+func messageContinuation(line string, next []string) []string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))] + "    "
+	var continuation []string
+	for _, nextLine := range next {
+		if !strings.HasPrefix(nextLine, indent) {
+			break
+		}
+		continuation = append(continuation, strings.TrimPrefix(nextLine, indent))
+	}
+	return continuation
+}
+
+// testifyMessage returns the error message of the testify assertion starting with the given lines.
+func testifyMessage(lines []string) string {
+	for i, line := range lines {
+		if testifyLocationRegexp.MatchString(line) {
+			return ""
+		}
+		match := testifyMessageRegexp.FindStringSubmatch(line)
+		if len(match) == 0 {
+			continue
+		}
+		message := []string{strings.TrimSpace(match[1])}
+		for _, next := range lines[i+1:] {
+			continuation := testifyContinuationRegexp.FindStringSubmatch(next)
+			if len(continuation) == 0 {
+				break
+			}
+			message = append(message, strings.TrimRight(continuation[1], " "))
+		}
+		return strings.Join(message, "\n")
+	}
+	return ""
+}
+
+// buildLocation returns the location of a compile error line in the build output, such as
+// "foo/foo.go:12:5: undefined: bar".
+func (r *locationResolver) buildLocation(line string) (Location, bool) {
+	match := locationRegexp.FindStringSubmatch(line)
+	if len(match) == 0 {
+		return Location{}, false
+	}
+	lineNumber, err := strconv.Atoi(match[2])
+	if err != nil {
+		return Location{}, false
+	}
+	return Location{
+		File:    r.resolveBuildFile(match[1]),
+		Line:    lineNumber,
+		Message: strings.TrimSpace(match[3]),
+	}, true
+}
+
+func (r *locationResolver) newLocation(pkg string, file string, line string) (Location, error) {
+	lineNumber, err := strconv.Atoi(line)
+	if err != nil {
+		return Location{}, err
+	}
+	return Location{
+		File: r.resolve(pkg, file),
+		Line: lineNumber,
+	}, nil
+}
+
+// resolve converts a file name from the test output into a path relative to the repository root. File names without a
+// directory are relative to the package directory. If the path cannot be resolved it is returned unchanged.
+func (r *locationResolver) resolve(pkg string, file string) string {
+	if r.repoRoot == "" {
+		return file
+	}
+	var absolute string
+	switch {
+	case filepath.IsAbs(file):
+		absolute = file
+	case !strings.ContainsAny(file, `/\`):
+		dir := r.packageDir(pkg)
+		if dir == "" {
+			return file
+		}
+		absolute = filepath.Join(dir, file)
+	default:
+		return file
+	}
+	return r.relativeToRepo(file, absolute)
+}
+
+// packageDir returns the directory of the package. Packages in the module under test are located in the module root,
+// other packages are looked up in the GOPATH. It returns an empty string if the directory is not found.
+func (r *locationResolver) packageDir(pkg string) string {
+	if r.modulePath != "" && (pkg == r.modulePath || strings.HasPrefix(pkg, r.modulePath+"/")) {
+		return filepath.Join(r.moduleRoot, filepath.FromSlash(strings.TrimPrefix(pkg, r.modulePath)))
+	}
+	if pkg == "" {
+		return ""
+	}
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		dir := filepath.Join(gopath, "src", filepath.FromSlash(pkg))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
+// resolveBuildFile converts a file name from the build output into a path relative to the repository root. Relative
+// file names are relative to the working directory of the go command, which is assumed to be the current working
+// directory if the file exists there. Otherwise, the file name is returned unchanged.
+func (r *locationResolver) resolveBuildFile(file string) string {
+	if r.repoRoot == "" {
+		return file
+	}
+	absolute := file
+	if !filepath.IsAbs(file) {
+		if r.workDir == "" {
+			return file
+		}
+		absolute = filepath.Join(r.workDir, filepath.FromSlash(file))
+		if _, err := os.Stat(absolute); err != nil {
+			return file
+		}
+	}
+	return r.relativeToRepo(file, absolute)
+}
+
+// relativeToRepo returns the absolute path relative to the repository root, or the original file name if the path is
+// outside the repository.
+func (r *locationResolver) relativeToRepo(file string, absolute string) string {
+	relative, err := filepath.Rel(r.repoRoot, absolute)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return file
+	}
+	return filepath.ToSlash(relative)
+}
@@ -1,6 +1,10 @@
 package parser
 
 import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
 	"strings"
 	"time"
 )
@@ -33,6 +37,215 @@ type TestCase struct {
 	Output string
 	// Cached indicates that the test results are cached and the tests have not actually been run.
 	Cached bool
+	// Locations are the source code locations mentioned in the output of a failed test case, such as the lines of
+	// failed assertions.
+	Locations []Location
+	// Failures contains the failed testify assertions in the output of this test case.
+	Failures []Assertion
+	// Attempts contains the individual runs of this test case if it ran more than once, for example with -count or
+	// when rerunning failed tests. Attempts from earlier runs of the same package are included. It is empty if the
+	// test case ran only once.
+	Attempts []TestAttempt
+	// Flaky indicates that at least one attempt of this test case passed and at least one failed.
+	Flaky bool
+	// Panic contains the details of the panic if this test case panicked, nil otherwise. The panic output, including the
+	// goroutine dump, is moved to the output of this test case even if go test reported it for a parent test.
+	Panic *Panic
+	// Races contains the data races the race detector reported while this test case was running.
+	Races []DataRace
+	// TimedOut indicates that this test case was still running when the test binary timed out.
+	TimedOut bool
+	// Fuzz contains the fuzzing statistics if this test case is a fuzz test run with -fuzz, nil otherwise.
+	Fuzz *FuzzSummary
+	// FailingInput is the path of the corpus file that made this fuzz test fail, relative to the package directory.
+	// It is set on the fuzz test if the input was found with -fuzz, and on the subtest running the corpus file
+	// otherwise.
+	FailingInput string
+	// Parent is the test case this subtest was started from, or nil for top-level test cases and for subtests whose
+	// parent is missing from the output.
+	Parent *TestCase `json:"-"`
+	// Children are the direct subtests of this test case in the order of Package.TestCases.
+	Children []*TestCase `json:"-"`
+}
+
+// Panic describes a panic in a test case.
+type Panic struct {
+	// Message is the value passed to panic, as printed in the "panic:" line.
+	Message string `json:"message"`
+	// Stack contains the stack frames of the panicking goroutine, innermost first.
+	Stack []StackFrame `json:"stack,omitempty"`
+}
+
+// StackFrame is a single function call in a goroutine stack trace.
+type StackFrame struct {
+	// Function is the fully qualified name of the called function.
+	Function string `json:"function"`
+	// File is the path of the source file. Files in the repository are relative to the repository root.
+	File string `json:"file"`
+	// Line is the line number in the file.
+	Line int `json:"line"`
+}
+
+// Origin returns the innermost stack frame outside the Go runtime and the testing package, which is usually the line
+// that caused the panic. It returns nil if there is no such frame.
+func (p *Panic) Origin() *StackFrame {
+	return originFrame(p.Stack)
+}
+
+// DataRace is a data race reported by the race detector.
+type DataRace struct {
+	// Accesses are the conflicting memory accesses, the current access first.
+	Accesses []RaceAccess `json:"accesses"`
+	// Goroutines are the goroutines involved in the race with the stack they were created at.
+	Goroutines []RaceGoroutine `json:"goroutines,omitempty"`
+	// Output is the full race report as printed by the race detector.
+	Output string `json:"output"`
+}
+
+// Description returns a one-line description of the race, such as
+// "read at foo.go:12 by goroutine 7, previous write at foo.go:15 by goroutine 6".
+func (r *DataRace) Description() string {
+	if len(r.Accesses) == 0 {
+		return "unrecognized race report"
+	}
+	parts := make([]string, 0, len(r.Accesses))
+	for _, access := range r.Accesses {
+		part := access.Kind
+		if access.Previous {
+			part = "previous " + part
+		}
+		if origin := access.Origin(); origin != nil {
+			part += fmt.Sprintf(" at %s:%d", origin.File, origin.Line)
+		}
+		if access.Goroutine != 0 {
+			part += fmt.Sprintf(" by goroutine %d", access.Goroutine)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RaceAccess is a single memory access in a data race.
+type RaceAccess struct {
+	// Kind is the kind of the access, such as "read", "write", or "atomic write".
+	Kind string `json:"kind"`
+	// Previous indicates that this access happened before the access the race was detected at.
+	Previous bool `json:"previous,omitempty"`
+	// Address is the memory address that was accessed.
+	Address string `json:"address"`
+	// Goroutine is the ID of the goroutine making the access, 1 for the main goroutine.
+	Goroutine int `json:"goroutine"`
+	// Stack contains the stack frames of the access, innermost first.
+	Stack []StackFrame `json:"stack,omitempty"`
+}
+
+// Origin returns the innermost stack frame outside the Go runtime and the testing package, or nil if there is no such
+// frame.
+func (a *RaceAccess) Origin() *StackFrame {
+	return originFrame(a.Stack)
+}
+
+// RaceGoroutine is a goroutine involved in a data race.
+type RaceGoroutine struct {
+	// ID is the ID of the goroutine.
+	ID int `json:"id"`
+	// State is the state of the goroutine at the time the race was detected, such as "running" or "finished".
+	State string `json:"state"`
+	// Stack contains the stack frames where the goroutine was created, innermost first.
+	Stack []StackFrame `json:"stack,omitempty"`
+}
+
+func originFrame(frames []StackFrame) *StackFrame {
+	for i, frame := range frames {
+		if frame.Function == "panic" ||
+			strings.HasPrefix(frame.Function, "runtime.") ||
+			strings.HasPrefix(frame.Function, "testing.") {
+			continue
+		}
+		return &frames[i]
+	}
+	return nil
+}
+
+// TestAttempt is the result of a single run of a test case.
+type TestAttempt struct {
+	// Result is the result of this attempt.
+	Result Result `json:"result"`
+	// Duration is the time it took to execute this attempt.
+	Duration time.Duration `json:"duration"`
+	// Output is the log output of this attempt.
+	Output string `json:"output,omitempty"`
+}
+
+// Location is a source code location mentioned in the test output.
+type Location struct {
+	// File is the path of the file relative to the repository root. If the path cannot be determined it contains the
+	// file name as printed in the output.
+	File string `json:"file"`
+	// Line is the line number in the file.
+	Line int `json:"line"`
+	// Message is the text printed after the location on the same line, if any.
+	Message string `json:"message,omitempty"`
+}
+
+// Assertion is a failed testify assertion, such as assert.Equal, in the output of a test case.
+type Assertion struct {
+	// Location is the first entry of the error trace, usually the line of the assertion.
+	Location Location `json:"location"`
+	// Message is the error message without the expected and actual values, such as "Not equal:".
+	Message string `json:"message"`
+	// Expected is the expected value if the assertion printed one.
+	Expected string `json:"expected,omitempty"`
+	// Actual is the actual value if the assertion printed one.
+	Actual string `json:"actual,omitempty"`
+	// Diff is the unified diff between the expected and the actual value if the assertion printed one.
+	Diff string `json:"diff,omitempty"`
+	// Messages contains the message arguments passed to the assertion, if any.
+	Messages string `json:"messages,omitempty"`
+}
+
+// FuzzSummary contains the statistics of a fuzz test from the last progress line printed while fuzzing.
+type FuzzSummary struct {
+	// Elapsed is the time spent fuzzing.
+	Elapsed time.Duration
+	// Execs is the number of times the fuzz function was called.
+	Execs int64
+	// ExecsPerSecond is the number of calls per second since the previous progress line.
+	ExecsPerSecond int64
+	// NewInteresting is the number of inputs added to the corpus because they expanded the code coverage.
+	NewInteresting int64
+	// TotalInteresting is the size of the corpus including the seed corpus and the cached inputs.
+	TotalInteresting int64
+	// Workers is the number of fuzzing processes.
+	Workers int
+}
+
+// Benchmark is the result of a benchmark function or sub-benchmark.
+type Benchmark struct {
+	// Name is the name of the benchmark without the GOMAXPROCS suffix. It may contain slashes (`/`) for
+	// sub-benchmarks.
+	Name string `json:"name"`
+	// Results contains one entry per result line, for example when running with -count or -cpu.
+	Results []BenchmarkResult `json:"results,omitempty"`
+	// Output is the log output of this benchmark.
+	Output string `json:"output,omitempty"`
+}
+
+// BenchmarkResult is a single result line of a benchmark, such as
+// "BenchmarkFoo-8  1000  1234 ns/op  56 B/op  2 allocs/op".
+type BenchmarkResult struct {
+	// Procs is the GOMAXPROCS value the benchmark ran with, or 0 if it was not printed.
+	Procs int `json:"procs,omitempty"`
+	// Iterations is the number of times the benchmark loop ran.
+	Iterations int64 `json:"iterations"`
+	// NsPerOp is the time per iteration in nanoseconds.
+	NsPerOp float64 `json:"nsPerOp"`
+	// BytesPerOp is the number of bytes allocated per iteration, or nil if memory allocations were not reported.
+	BytesPerOp *int64 `json:"bytesPerOp,omitempty"`
+	// AllocsPerOp is the number of allocations per iteration, or nil if memory allocations were not reported.
+	AllocsPerOp *int64 `json:"allocsPerOp,omitempty"`
+	// Metrics holds the other reported values by unit, such as MB/s or the custom units of b.ReportMetric.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
 }
 
 // ID returns the Name of the test case without slashes
@@ -49,6 +262,48 @@ func (t *TestCase) EndTime() *time.Time {
 	return &endTime
 }
 
+// ShortName returns the name of the test case without the name of its parent, for example "case" for "TestFoo/case".
+func (t *TestCase) ShortName() string {
+	if t.Parent == nil {
+		return t.Name
+	}
+	return strings.TrimPrefix(t.Name, t.Parent.Name+"/")
+}
+
+// Depth returns the number of parents of the test case, 0 for top-level test cases.
+func (t *TestCase) Depth() int {
+	depth := 0
+	for parent := t.Parent; parent != nil; parent = parent.Parent {
+		depth++
+	}
+	return depth
+}
+
+// FailedSubtests returns the number of failed subtests below this test case at any depth.
+func (t *TestCase) FailedSubtests() int {
+	failed := 0
+	for _, child := range t.Children {
+		if child.Result == ResultFail {
+			failed++
+		}
+		failed += child.FailedSubtests()
+	}
+	return failed
+}
+
+// RerunPattern returns the -run pattern of go test that runs the failing input of a fuzz test, such as
+// "FuzzFoo/abc123", or an empty string if the test case has no failing input.
+func (t *TestCase) RerunPattern() string {
+	if t.FailingInput == "" {
+		return ""
+	}
+	name := t.Name
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[:i]
+	}
+	return name + "/" + path.Base(t.FailingInput)
+}
+
 // Package is the structure for all tests in a package.
 type Package struct {
 	// StartTime marks the earliest time this package was seen in the log output.
@@ -62,9 +317,14 @@ type Package struct {
 	// Coverage is the percentage of code coverage in this package, or a negative number if no coverage data is
 	// present.
 	Coverage *float64
+	// NoStatements indicates that coverage was enabled, but the package has no statements to cover.
+	NoStatements bool
 	// Output is the text output of a generic failure (e.g. a syntax error)
 	Output string
-	// TestCases is a list of test cases run in this package. Subtests are included as separate test cases.
+	// Locations are the source code locations of the compile errors if the package failed to build.
+	Locations []Location
+	// TestCases is a list of test cases run in this package. Subtests are included as separate test cases, use
+	// RootTestCases to walk the test cases as a tree.
 	TestCases []*TestCase
 	// TestCasesByName holds the test cases mapped by name.
 	TestCasesByName map[string]*TestCase
@@ -72,6 +332,69 @@ type Package struct {
 	Reason string
 	// Cached indicates that the results came from the go test cache.
 	Cached bool
+	// Benchmarks is a list of benchmarks run in this package. Benchmarks are only listed as test cases if they failed
+	// or were skipped.
+	Benchmarks []*Benchmark
+	// Races contains the data races reported outside of test cases, for example in TestMain or in goroutines still
+	// running after the tests finished.
+	Races []DataRace
+	// Timeout is set if the tests in this package were stopped because they exceeded the -timeout of go test.
+	Timeout *Timeout
+	// CoverageByFile contains the coverage of the source files in this package, sorted by file name. It is only filled
+	// in if a coverage profile was passed to gotestfmt.
+	CoverageByFile []FileCoverage
+}
+
+// FileCoverage is the statement coverage of a single source file from a coverage profile.
+type FileCoverage struct {
+	// File is the path of the file relative to the repository root. If the file cannot be found it contains the name
+	// from the coverage profile, such as "example.com/foo/foo.go".
+	File string `json:"file"`
+	// Statements is the number of statements in the file.
+	Statements int `json:"statements"`
+	// CoveredStatements is the number of statements that were executed at least once.
+	CoveredStatements int `json:"coveredStatements"`
+	// Functions contains the coverage of the functions declared in the file. It is empty if the source file cannot be
+	// found.
+	Functions []FunctionCoverage `json:"functions,omitempty"`
+}
+
+// Percent returns the percentage of covered statements rounded to one decimal, or 100 if the file has no statements.
+func (f FileCoverage) Percent() float64 {
+	return coveragePercent(f.CoveredStatements, f.Statements)
+}
+
+// FunctionCoverage is the statement coverage of a single function.
+type FunctionCoverage struct {
+	// Name is the name of the function, prefixed with the receiver type for methods, such as "Foo.Bar".
+	Name string `json:"name"`
+	// Line is the line the function is declared on.
+	Line int `json:"line"`
+	// Statements is the number of statements in the function.
+	Statements int `json:"statements"`
+	// CoveredStatements is the number of statements that were executed at least once.
+	CoveredStatements int `json:"coveredStatements"`
+}
+
+// Percent returns the percentage of covered statements rounded to one decimal, or 100 if the function has no
+// statements.
+func (f FunctionCoverage) Percent() float64 {
+	return coveragePercent(f.CoveredStatements, f.Statements)
+}
+
+func coveragePercent(covered int, statements int) float64 {
+	if statements == 0 {
+		return 100
+	}
+	return math.Round(float64(covered)*1000/float64(statements)) / 10
+}
+
+// Timeout describes a test binary stopped because it exceeded the -timeout flag of go test.
+type Timeout struct {
+	// After is the timeout that was exceeded.
+	After time.Duration
+	// RunningTests contains the names of the test cases that were still running.
+	RunningTests []string
 }
 
 func (p *Package) EndTime() *time.Time {
@@ -82,6 +405,64 @@ func (p *Package) EndTime() *time.Time {
 	return &t
 }
 
+// RootTestCases returns the test cases without a parent. Their subtests can be reached through TestCase.Children.
+func (p *Package) RootTestCases() []*TestCase {
+	var roots []*TestCase
+	for _, tc := range p.TestCases {
+		if tc.Parent == nil {
+			roots = append(roots, tc)
+		}
+	}
+	return roots
+}
+
+// LeastCoveredFiles returns up to n files with statements from CoverageByFile, the lowest coverage first. Files with
+// the same coverage are ordered by the number of uncovered statements, the highest first.
+func (p *Package) LeastCoveredFiles(n int) []FileCoverage {
+	var files []FileCoverage
+	for _, file := range p.CoverageByFile {
+		if file.Statements > 0 {
+			files = append(files, file)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Percent() != files[j].Percent() {
+			return files[i].Percent() < files[j].Percent()
+		}
+		return files[i].Statements-files[i].CoveredStatements > files[j].Statements-files[j].CoveredStatements
+	})
+	if n >= 0 && len(files) > n {
+		files = files[:n]
+	}
+	return files
+}
+
+// linkTestCases sets the Parent and Children fields of the test cases based on their names. The parent of a subtest is
+// the closest test case whose name is a prefix of the subtest name up to a slash.
+func (p *Package) linkTestCases() {
+	byName := make(map[string]*TestCase, len(p.TestCases))
+	for _, tc := range p.TestCases {
+		tc.Parent = nil
+		tc.Children = nil
+		byName[tc.Name] = tc
+	}
+	for _, tc := range p.TestCases {
+		name := tc.Name
+		for {
+			i := strings.LastIndex(name, "/")
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+			if parent, ok := byName[name]; ok {
+				tc.Parent = parent
+				parent.Children = append(parent.Children, tc)
+				break
+			}
+		}
+	}
+}
+
 // ID returns the Name of the package without dots and slashes
 func (p *Package) ID() string {
 	return strings.Replace(
@@ -116,7 +497,12 @@ type Downloads struct {
 	Reason string `json:"reason"`
 }
 
+// SchemaVersion is the version of the JSON representation of ParseResult. It is increased when the format changes in
+// a way older readers cannot handle.
+const SchemaVersion = 1
+
 // ParseResult is an overall structure for parser results, containing the prefix text, downloads and packagesByName.
+// The JSON representation includes the SchemaVersion.
 type ParseResult struct {
 	Prefix    []string  `json:"prefix"`
 	Downloads Downloads `json:"downloads"`
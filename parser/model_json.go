@@ -3,24 +3,47 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 )
 
 type tmpTestCase struct {
-	Name     string   `json:"name"`
-	Result   Result   `json:"result"`
-	Duration string   `json:"duration"`
-	Coverage *float64 `json:"coverage"`
-	Output   string   `json:"output"`
+	StartTime    *time.Time    `json:"startTime,omitempty"`
+	Name         string        `json:"name"`
+	Result       Result        `json:"result"`
+	Duration     string        `json:"duration"`
+	Coverage     *float64      `json:"coverage"`
+	Output       string        `json:"output"`
+	Cached       bool          `json:"cached,omitempty"`
+	Locations    []Location    `json:"locations,omitempty"`
+	Failures     []Assertion   `json:"failures,omitempty"`
+	Attempts     []TestAttempt `json:"attempts,omitempty"`
+	Flaky        bool          `json:"flaky,omitempty"`
+	Panic        *Panic        `json:"panic,omitempty"`
+	Races        []DataRace    `json:"races,omitempty"`
+	TimedOut     bool          `json:"timedOut,omitempty"`
+	Fuzz         *FuzzSummary  `json:"fuzz,omitempty"`
+	FailingInput string        `json:"failingInput,omitempty"`
 }
 
 func (t *TestCase) MarshalJSON() ([]byte, error) {
 	tmp := tmpTestCase{
-		Name:     t.Name,
-		Result:   t.Result,
-		Duration: t.Duration.String(),
-		Coverage: t.Coverage,
-		Output:   t.Output,
+		StartTime:    nonZeroTime(t.StartTime),
+		Name:         t.Name,
+		Result:       t.Result,
+		Duration:     t.Duration.String(),
+		Coverage:     t.Coverage,
+		Output:       t.Output,
+		Cached:       t.Cached,
+		Locations:    t.Locations,
+		Failures:     t.Failures,
+		Attempts:     t.Attempts,
+		Flaky:        t.Flaky,
+		Panic:        t.Panic,
+		Races:        t.Races,
+		TimedOut:     t.TimedOut,
+		Fuzz:         t.Fuzz,
+		FailingInput: t.FailingInput,
 	}
 	return json.Marshal(tmp)
 }
@@ -38,33 +61,93 @@ func (t *TestCase) UnmarshalJSON(data []byte) error {
 			return fmt.Errorf("failed to parse duration: %s (%w)", tmp.Duration, err)
 		}
 	}
+	t.StartTime = tmp.StartTime
 	t.Name = tmp.Name
 	t.Result = tmp.Result
 	t.Duration = duration
 	t.Coverage = tmp.Coverage
 	t.Output = tmp.Output
+	t.Cached = tmp.Cached
+	t.Locations = tmp.Locations
+	t.Failures = tmp.Failures
+	t.Attempts = tmp.Attempts
+	t.Flaky = tmp.Flaky
+	t.Panic = tmp.Panic
+	t.Races = tmp.Races
+	t.TimedOut = tmp.TimedOut
+	t.Fuzz = tmp.Fuzz
+	t.FailingInput = tmp.FailingInput
+	return nil
+}
+
+type tmpTestAttempt struct {
+	Result   Result `json:"result"`
+	Duration string `json:"duration"`
+	Output   string `json:"output,omitempty"`
+}
+
+func (t TestAttempt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tmpTestAttempt{
+		Result:   t.Result,
+		Duration: t.Duration.String(),
+		Output:   t.Output,
+	})
+}
+
+func (t *TestAttempt) UnmarshalJSON(data []byte) error {
+	var tmp tmpTestAttempt
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	var duration time.Duration
+	var err error
+	if tmp.Duration != "" {
+		duration, err = time.ParseDuration(tmp.Duration)
+		if err != nil {
+			return fmt.Errorf("failed to parse duration: %s (%w)", tmp.Duration, err)
+		}
+	}
+	t.Result = tmp.Result
+	t.Duration = duration
+	t.Output = tmp.Output
 	return nil
 }
 
 type tmpPackage struct {
-	Name      string      `json:"name"`
-	Result    Result      `json:"result"`
-	Duration  string      `json:"duration"`
-	Coverage  *float64    `json:"coverage"`
-	Output    string      `json:"output"`
-	TestCases []*TestCase `json:"testcases"`
-	Reason    string      `json:"reason"`
+	StartTime      *time.Time     `json:"startTime,omitempty"`
+	Name           string         `json:"name"`
+	Result         Result         `json:"result"`
+	Duration       string         `json:"duration"`
+	Coverage       *float64       `json:"coverage"`
+	NoStatements   bool           `json:"noStatements,omitempty"`
+	Output         string         `json:"output"`
+	Locations      []Location     `json:"locations,omitempty"`
+	TestCases      []*TestCase    `json:"testcases"`
+	Reason         string         `json:"reason"`
+	Cached         bool           `json:"cached,omitempty"`
+	Benchmarks     []*Benchmark   `json:"benchmarks,omitempty"`
+	Races          []DataRace     `json:"races,omitempty"`
+	Timeout        *Timeout       `json:"timeout,omitempty"`
+	CoverageByFile []FileCoverage `json:"coverageByFile,omitempty"`
 }
 
 func (p *Package) MarshalJSON() ([]byte, error) {
 	tmp := tmpPackage{
-		Name:      p.Name,
-		Result:    p.Result,
-		Duration:  p.Duration.String(),
-		Coverage:  p.Coverage,
-		Output:    p.Output,
-		TestCases: p.TestCases,
-		Reason:    p.Reason,
+		StartTime:      nonZeroTime(p.StartTime),
+		Name:           p.Name,
+		Result:         p.Result,
+		Duration:       p.Duration.String(),
+		Coverage:       p.Coverage,
+		NoStatements:   p.NoStatements,
+		Output:         p.Output,
+		Locations:      p.Locations,
+		TestCases:      p.TestCases,
+		Reason:         p.Reason,
+		Cached:         p.Cached,
+		Benchmarks:     p.Benchmarks,
+		Races:          p.Races,
+		Timeout:        p.Timeout,
+		CoverageByFile: p.CoverageByFile,
 	}
 	return json.Marshal(tmp)
 }
@@ -82,12 +165,189 @@ func (p *Package) UnmarshalJSON(data []byte) error {
 			return fmt.Errorf("failed to parse duration: %s (%w)", tmp.Duration, err)
 		}
 	}
+	p.StartTime = tmp.StartTime
 	p.Name = tmp.Name
 	p.Result = tmp.Result
 	p.Duration = duration
 	p.Coverage = tmp.Coverage
+	p.NoStatements = tmp.NoStatements
 	p.Output = tmp.Output
+	p.Locations = tmp.Locations
 	p.TestCases = tmp.TestCases
 	p.Reason = tmp.Reason
+	p.Cached = tmp.Cached
+	p.Benchmarks = tmp.Benchmarks
+	p.Races = tmp.Races
+	p.Timeout = tmp.Timeout
+	p.CoverageByFile = tmp.CoverageByFile
+	p.TestCasesByName = make(map[string]*TestCase, len(p.TestCases))
+	for _, tc := range p.TestCases {
+		p.TestCasesByName[tc.Name] = tc
+	}
+	p.linkTestCases()
+	return nil
+}
+
+type tmpTimeout struct {
+	After        string   `json:"after"`
+	RunningTests []string `json:"runningTests,omitempty"`
+}
+
+func (t Timeout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tmpTimeout{
+		After:        t.After.String(),
+		RunningTests: t.RunningTests,
+	})
+}
+
+func (t *Timeout) UnmarshalJSON(data []byte) error {
+	var tmp tmpTimeout
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	var after time.Duration
+	var err error
+	if tmp.After != "" {
+		after, err = time.ParseDuration(tmp.After)
+		if err != nil {
+			return fmt.Errorf("failed to parse timeout: %s (%w)", tmp.After, err)
+		}
+	}
+	t.After = after
+	t.RunningTests = tmp.RunningTests
 	return nil
 }
+
+type tmpFuzzSummary struct {
+	Elapsed          string `json:"elapsed"`
+	Execs            int64  `json:"execs"`
+	ExecsPerSecond   int64  `json:"execsPerSecond"`
+	NewInteresting   int64  `json:"newInteresting"`
+	TotalInteresting int64  `json:"totalInteresting"`
+	Workers          int    `json:"workers,omitempty"`
+}
+
+func (f FuzzSummary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tmpFuzzSummary{
+		Elapsed:          f.Elapsed.String(),
+		Execs:            f.Execs,
+		ExecsPerSecond:   f.ExecsPerSecond,
+		NewInteresting:   f.NewInteresting,
+		TotalInteresting: f.TotalInteresting,
+		Workers:          f.Workers,
+	})
+}
+
+func (f *FuzzSummary) UnmarshalJSON(data []byte) error {
+	var tmp tmpFuzzSummary
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	var elapsed time.Duration
+	var err error
+	if tmp.Elapsed != "" {
+		elapsed, err = time.ParseDuration(tmp.Elapsed)
+		if err != nil {
+			return fmt.Errorf("failed to parse fuzzing time: %s (%w)", tmp.Elapsed, err)
+		}
+	}
+	f.Elapsed = elapsed
+	f.Execs = tmp.Execs
+	f.ExecsPerSecond = tmp.ExecsPerSecond
+	f.NewInteresting = tmp.NewInteresting
+	f.TotalInteresting = tmp.TotalInteresting
+	f.Workers = tmp.Workers
+	return nil
+}
+
+// nonZeroTime returns nil for the zero time, which the parser records if the input has no timestamps.
+func nonZeroTime(t *time.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+type tmpDownloads struct {
+	Packages  []*Download `json:"packages"`
+	Failed    bool        `json:"failed"`
+	StartTime *time.Time  `json:"startTime,omitempty"`
+	EndTime   *time.Time  `json:"endTime,omitempty"`
+	Reason    string      `json:"reason"`
+}
+
+func (d Downloads) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tmpDownloads{
+		Packages:  d.Packages,
+		Failed:    d.Failed,
+		StartTime: nonZeroTime(d.StartTime),
+		EndTime:   nonZeroTime(d.EndTime),
+		Reason:    d.Reason,
+	})
+}
+
+func (d *Downloads) UnmarshalJSON(data []byte) error {
+	var tmp tmpDownloads
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	d.Packages = tmp.Packages
+	d.Failed = tmp.Failed
+	d.StartTime = tmp.StartTime
+	d.EndTime = tmp.EndTime
+	d.Reason = tmp.Reason
+	return nil
+}
+
+type tmpParseResult struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Prefix        []string  `json:"prefix"`
+	Downloads     Downloads `json:"downloads"`
+	Packages      []Package `json:"packages"`
+}
+
+func (r ParseResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tmpParseResult{
+		SchemaVersion: SchemaVersion,
+		Prefix:        r.Prefix,
+		Downloads:     r.Downloads,
+		Packages:      r.Packages,
+	})
+}
+
+func (r *ParseResult) UnmarshalJSON(data []byte) error {
+	var tmp tmpParseResult
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	if tmp.SchemaVersion > SchemaVersion {
+		return fmt.Errorf(
+			"unsupported schema version: %d (this version of gotestfmt supports up to %d)",
+			tmp.SchemaVersion,
+			SchemaVersion,
+		)
+	}
+	r.Prefix = tmp.Prefix
+	r.Downloads = tmp.Downloads
+	r.Packages = tmp.Packages
+	return nil
+}
+
+// WriteJSONReport writes the parse result as an indented JSON document to the target. ReadJSONReport reads it back.
+func WriteJSONReport(target io.Writer, result *ParseResult) error {
+	encoder := json.NewEncoder(target)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode JSON report (%w)", err)
+	}
+	return nil
+}
+
+// ReadJSONReport reads a parse result written by WriteJSONReport.
+func ReadJSONReport(input io.Reader) (*ParseResult, error) {
+	result := &ParseResult{}
+	if err := json.NewDecoder(input).Decode(result); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON report (%w)", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// panicRegexp matches the first line of a panic, such as "panic: runtime error: index out of range [recovered]".
+var panicRegexp = regexp.MustCompile(`^panic: (?P<Message>.*?)(?: \[recovered(?:, repanicked)?\])?$`)
+
+// goroutineRegexp matches the header of a goroutine in a stack trace, such as "goroutine 7 [running]:".
+var goroutineRegexp = regexp.MustCompile(`^goroutine [0-9]+ \[(?P<State>[^]]*)\]:$`)
+
+// stackFrameFileRegexp matches the source location of a stack frame, such as "	/path/to/foo.go:12 +0x1d".
+var stackFrameFileRegexp = regexp.MustCompile(`^\t(?P<File>.+\.go):(?P<Line>[0-9]+)(?: \+0x[0-9a-f]+)?$`)
+
+// extractPanic looks for a panic in the output of a test case or package in the given package. It returns the output
+// before the panic, the panic output including the goroutine dump, and the parsed panic. If there is no panic, the
+// output is returned unchanged with a nil panic.
+func (r *locationResolver) extractPanic(pkg string, output string) (string, string, *Panic) {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		match := panicRegexp.FindStringSubmatch(line)
+		if len(match) == 0 {
+			continue
+		}
+		return strings.TrimRight(strings.Join(lines[:i], "\n"), "\n"),
+			strings.Join(lines[i:], "\n"),
+			&Panic{
+				Message: match[1],
+				Stack:   r.parseStack(pkg, lines[i+1:]),
+			}
+	}
+	return output, "", nil
+}
+
+// parseStack parses the stack frames of the running goroutine, or the first goroutine if none is running.
+func (r *locationResolver) parseStack(pkg string, lines []string) []StackFrame {
+	start := -1
+	for i, line := range lines {
+		match := goroutineRegexp.FindStringSubmatch(line)
+		if len(match) == 0 {
+			continue
+		}
+		if start == -1 {
+			start = i + 1
+		}
+		if match[1] == "running" {
+			start = i + 1
+			break
+		}
+	}
+	if start == -1 {
+		return nil
+	}
+	var frames []StackFrame
+	for i := start; i+1 < len(lines) && lines[i] != ""; i += 2 {
+		match := stackFrameFileRegexp.FindStringSubmatch(lines[i+1])
+		if len(match) == 0 {
+			break
+		}
+		if strings.HasPrefix(lines[i], "created by ") {
+			continue
+		}
+		lineNumber, err := strconv.Atoi(match[2])
+		if err != nil {
+			break
+		}
+		frames = append(frames, StackFrame{
+			Function: stackFrameFunction(lines[i]),
+			File:     r.resolve(pkg, match[1]),
+			Line:     lineNumber,
+		})
+	}
+	return frames
+}
+
+// stackFrameFunction removes the arguments from the function line of a stack frame, such as
+// "main.foo(0x1, {0x2, 0x3})".
+func stackFrameFunction(line string) string {
+	if !strings.HasSuffix(line, ")") {
+		return line
+	}
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
@@ -15,16 +15,33 @@ import (
 // to the output according to the passed configuration.
 // The result are three channels: the prefix text before any recognized action, the download channel will receive either
 // zero or one result and then be closed. Once the downloads channel is closed the parsed package results will be
-// streamed over the second result.
+// streamed over the second result. Each package is sent as soon as its final pass, fail or skip line is seen, packages
+// that never receive such a line are sent sorted by name once the input ends.
 func Parse(
 	evts <-chan tokenizer.Event,
 ) (<-chan string, <-chan *Downloads, <-chan *Package) {
+	prefixChannel, downloadsChannel, packagesChannel, _ := ParseWithErrors(evts)
+	return prefixChannel, downloadsChannel, packagesChannel
+}
+
+// ParseWithErrors works like Parse, but also returns an error channel. Errors do not stop the parsing, the events
+// causing them are skipped. The error channel receives the first error, if any, and is closed after the packages
+// channel has been closed.
+func ParseWithErrors(
+	evts <-chan tokenizer.Event,
+) (<-chan string, <-chan *Downloads, <-chan *Package, <-chan error) {
 	prefixChannel := make(chan string)
 	downloadsChannel := make(chan *Downloads)
 	downloadsFailureReason := make(chan string)
 	packagesChannel := make(chan *Package)
-	go parse(evts, prefixChannel, downloadsChannel, downloadsFailureReason, packagesChannel)
-	return prefixChannel, downloadsChannel, packagesChannel
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		if err := parse(evts, prefixChannel, downloadsChannel, downloadsFailureReason, packagesChannel); err != nil {
+			errs <- err
+		}
+	}()
+	return prefixChannel, downloadsChannel, packagesChannel, errs
 }
 
 var downloadErrors = []*regexp.Regexp{
@@ -39,7 +56,7 @@ func parse(
 	downloadsChannel chan *Downloads,
 	downloadsFailureReason chan string,
 	packagesChannel chan *Package,
-) {
+) (firstErr error) {
 	outputStarted := false
 	downloadTracker := &downloadsTracker{
 		prefixChannel:          prefixChannel,
@@ -50,8 +67,11 @@ func parse(
 		target:                 downloadsChannel,
 	}
 	pkgTracker := &packageTracker{
-		packagesByName: map[string]*Package{},
-		target:         packagesChannel,
+		packagesByName:   map[string]*Package{},
+		target:           packagesChannel,
+		locations:        newLocationResolver(),
+		previousAttempts: map[string]map[string][]TestAttempt{},
+		buildOutputs:     map[string][]string{},
 	}
 
 	defer func() {
@@ -60,18 +80,49 @@ func parse(
 		close(packagesChannel)
 	}()
 
+	recordError := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
 	var prevErroredDownload string
 	var prevErroredPkg string
+	var lastPkg string
+	var lastTest string
+	// strayOutput holds the lines that cannot be attributed to a package after the downloads have been sent, because the
+	// package they followed has already been written. They are added to the output of the next package, lines after the
+	// last package are dropped.
+	var strayOutput [][]byte
+	// addLateOutput adds a line that arrived after the downloads have been sent to the package seen last, or to the next
+	// package if that one has already been written.
+	addLateOutput := func(line []byte) {
+		if lastPkg != "" {
+			pkgTracker.AddOutput(lastPkg, "", line)
+			return
+		}
+		strayOutput = append(strayOutput, line)
+	}
 	for {
 		evt, ok := <-evts
 		if !ok {
-			return
+			return firstErr
 		}
 
+		if (evt.Action == tokenizer.ActionBenchmark || evt.Action == tokenizer.ActionFuzz) && evt.Package == "" {
+			// Without the package name the benchmark or fuzz test cannot be attributed, so we keep it as plain output.
+			evt.Action = tokenizer.ActionStdout
+		}
 		if evt.Action != tokenizer.ActionStdout {
 			outputStarted = true
 		}
 		if evt.Action != tokenizer.ActionDownload && evt.Action != tokenizer.ActionDownloadFailed && evt.Package != "" {
+			lastPkg = evt.Package
+			lastTest = evt.Test
+			for _, line := range strayOutput {
+				pkgTracker.AddOutput(evt.Package, "", line)
+			}
+			strayOutput = nil
 			pkgTracker.SetTestStartTime(evt.Package, evt.Test, evt.Received)
 			if evt.Elapsed != 0 {
 				pkgTracker.SetTestElapsed(evt.Package, evt.Test, evt.Elapsed)
@@ -105,6 +156,9 @@ func parse(
 			if len(evt.Output) > 0 {
 				pkgTracker.AddReason(evt.Package, string(evt.Output))
 			}
+			if evt.FailedBuild != "" && evt.Test == "" {
+				pkgTracker.AddBuildFailure(evt.Package, evt.FailedBuild)
+			}
 		case tokenizer.ActionPass:
 			pkgTracker.SetResult(evt.Package, evt.Test, ResultPass)
 			if len(evt.Output) > 0 {
@@ -116,33 +170,63 @@ func parse(
 				pkgTracker.AddReason(evt.Package, string(evt.Output))
 			}
 		case tokenizer.ActionDownload:
-			downloadTracker.Add(
+			if downloadTracker.downloadsFinished {
+				// The downloads have already been sent with the first package, so we keep the line as output.
+				addLateOutput([]byte(fmt.Sprintf("go: downloading %s %s", evt.Package, evt.Version)))
+				break
+			}
+			recordError(downloadTracker.Add(
 				evt.Package,
 				evt.Version,
-			)
+				evt.Received,
+			))
 		case tokenizer.ActionDownloadFailed:
+			if downloadTracker.downloadsFinished {
+				addLateOutput([]byte(fmt.Sprintf("go: %s@%s: %s", evt.Package, evt.Version, evt.Output)))
+				break
+			}
 			prevErroredDownload = evt.Package
-			downloadTracker.SetDownloadFailed(evt.Package, evt.Version)
-			downloadTracker.AddReason(evt.Package, evt.Output)
+			recordError(downloadTracker.SetDownloadFailed(evt.Package, evt.Version, evt.Received))
+			recordError(downloadTracker.AddReason(evt.Package, evt.Output, evt.Received))
+		case tokenizer.ActionCoverageNoStatements:
+			pkgTracker.SetNoStatements(evt.Package)
 		case tokenizer.ActionPackage:
 			pkgTracker.SetResult(evt.Package, "", ResultFail)
 			prevErroredPkg = evt.Package
+		case tokenizer.ActionBuildOutput:
+			pkgTracker.AddBuildOutput(evt.ImportPath, evt.Output)
+		case tokenizer.ActionRun:
+			pkgTracker.StartAttempt(evt.Package, evt.Test)
+		case tokenizer.ActionBench:
+			pkgTracker.AddOutput(evt.Package, evt.Test, evt.Output)
+		case tokenizer.ActionBenchmark:
+			if err := pkgTracker.AddBenchmarkResult(evt.Package, evt.Test, evt.Output); err != nil {
+				// Lines we cannot read are not an error, they are kept as plain output.
+				pkgTracker.AddOutput(evt.Package, evt.Test, evt.Output)
+			}
+		case tokenizer.ActionFuzz:
+			if err := pkgTracker.AddFuzzProgress(evt.Package, evt.Test, evt.Output); err != nil {
+				pkgTracker.AddOutput(evt.Package, evt.Test, evt.Output)
+			}
 		case tokenizer.ActionStdout:
-			if evt.JSON {
+			if evt.JSON && evt.Package != "" {
 				// We have a JSON-encoded output, that makes things much easier.
 				pkgTracker.AddOutput(evt.Package, evt.Test, evt.Output)
 			} else if len(evt.Output) > 0 {
 				// We don't have a JSON output, so this must be an error.
 				foundDLError := false
 				for _, dlError := range downloadErrors {
+					if downloadTracker.downloadsFinished {
+						break
+					}
 					if submatch := dlError.FindSubmatch(evt.Output); len(submatch) > 0 {
 						if len(submatch) > 1 {
 							pkgName := string(submatch[1])
-							downloadTracker.SetDownloadFailed(pkgName, "")
-							downloadTracker.AddReason(pkgName, evt.Output)
+							recordError(downloadTracker.SetDownloadFailed(pkgName, "", evt.Received))
+							recordError(downloadTracker.AddReason(pkgName, evt.Output, evt.Received))
 							prevErroredDownload = pkgName
 						} else {
-							downloadTracker.SetFailureReason(submatch[0])
+							recordError(downloadTracker.SetFailureReason(submatch[0]))
 							prevErroredDownload = "*"
 						}
 						foundDLError = true
@@ -152,9 +236,9 @@ func parse(
 				if !foundDLError {
 					if prevErroredDownload != "" {
 						if prevErroredDownload == "*" {
-							downloadTracker.SetFailureReason(evt.Output)
+							recordError(downloadTracker.SetFailureReason(evt.Output))
 						} else {
-							downloadTracker.AddReason(prevErroredDownload, evt.Output)
+							recordError(downloadTracker.AddReason(prevErroredDownload, evt.Output, evt.Received))
 						}
 					} else if prevErroredPkg != "" {
 						pkgTracker.AddOutput(prevErroredPkg, "", evt.Output)
@@ -162,13 +246,14 @@ func parse(
 						prefixChannel <- string(evt.Output)
 					} else if strings.HasPrefix(string(evt.Output), "exit status ") {
 						// Ignore go-acc exit status reporting.
+					} else if lastPkg != "" {
+						// We don't know where this output belongs (did you use -json on go test?), so we keep it
+						// with the last package or test we have seen.
+						pkgTracker.AddOutput(lastPkg, lastTest, evt.Output)
+					} else if !downloadTracker.downloadsFinished {
+						prefixChannel <- string(evt.Output)
 					} else {
-						panic(
-							fmt.Errorf(
-								"unexpected output encountered: %s (Did you use -json on go test?)",
-								evt.Output,
-							),
-						)
+						addLateOutput(evt.Output)
 					}
 				}
 			}
@@ -179,6 +264,31 @@ func parse(
 			prevErroredDownload = ""
 			prevErroredPkg = ""
 		}
+		if isPackageFinished(evt) {
+			// The renderer only starts reading packages once the downloads are closed.
+			downloadTracker.Write()
+			pkgTracker.WritePackage(evt.Package)
+			// Later output must not recreate the package that has just been written.
+			lastPkg = ""
+			lastTest = ""
+		}
+	}
+}
+
+// isPackageFinished returns true if the event is the final package-level result line, such as "ok", "FAIL" or "?".
+func isPackageFinished(evt tokenizer.Event) bool {
+	if evt.Package == "" || evt.Test != "" {
+		return false
+	}
+	switch evt.Action {
+	case tokenizer.ActionPass:
+		return true
+	case tokenizer.ActionFail:
+		return true
+	case tokenizer.ActionSkip:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -186,9 +296,18 @@ type packageTracker struct {
 	packages       []*Package
 	packagesByName map[string]*Package
 	target         chan<- *Package
+	locations      *locationResolver
+	// previousAttempts holds the attempts of the test cases in packages that have already been written, so reruns of
+	// the same package can be recognized.
+	previousAttempts map[string]map[string][]TestAttempt
+	// buildOutputs holds the build output by import path until a package reports that it failed because of the build.
+	buildOutputs map[string][]string
 }
 
 func (p *packageTracker) AddOutput(pkg string, test string, output []byte) {
+	if pkg == "" {
+		return
+	}
 	pkgObj := p.ensurePackage(pkg)
 	if test == "" {
 		pkgObj.Output = pkgObj.Output + string(output) + "\n"
@@ -198,6 +317,74 @@ func (p *packageTracker) AddOutput(pkg string, test string, output []byte) {
 	testCase.Output = testCase.Output + string(output) + "\n"
 }
 
+// AddBuildOutput records a line of build output for the given import path. The "# package" header lines are left out.
+func (p *packageTracker) AddBuildOutput(importPath string, output []byte) {
+	if importPath == "" || strings.HasPrefix(string(output), "# ") {
+		return
+	}
+	p.buildOutputs[importPath] = append(p.buildOutputs[importPath], string(output))
+}
+
+// AddBuildFailure adds the build output of the failed import path to the package output and records the locations of
+// the compile errors. The build output is kept because several packages may fail because of the same import path.
+func (p *packageTracker) AddBuildFailure(pkg string, importPath string) {
+	if pkg == "" {
+		return
+	}
+	pkgObj := p.ensurePackage(pkg)
+	for _, line := range p.buildOutputs[importPath] {
+		pkgObj.Output = pkgObj.Output + line + "\n"
+		if location, ok := p.locations.buildLocation(line); ok {
+			pkgObj.Locations = append(pkgObj.Locations, location)
+		}
+	}
+}
+
+// AddFuzzProgress parses a fuzzing progress line and updates the fuzzing statistics of the test case. The statistics
+// are left unchanged if the line cannot be parsed.
+func (p *packageTracker) AddFuzzProgress(pkg string, test string, line []byte) error {
+	if pkg == "" || test == "" {
+		return nil
+	}
+	testCase := p.ensureTest(p.ensurePackage(pkg), test)
+	fuzz := FuzzSummary{}
+	if testCase.Fuzz != nil {
+		fuzz = *testCase.Fuzz
+	}
+	if err := parseFuzzProgress(&fuzz, string(line)); err != nil {
+		return fmt.Errorf("failed to parse fuzz progress of %s in package %s (%w)", test, pkg, err)
+	}
+	testCase.Fuzz = &fuzz
+	return nil
+}
+
+// AddBenchmarkResult parses a benchmark result line and adds it to the benchmark in the package.
+func (p *packageTracker) AddBenchmarkResult(pkg string, test string, line []byte) error {
+	if pkg == "" {
+		return nil
+	}
+	name, result, err := parseBenchmarkResult(test, string(line))
+	if err != nil {
+		return fmt.Errorf("failed to parse benchmark result in package %s (%w)", pkg, err)
+	}
+	benchmark := ensureBenchmark(p.ensurePackage(pkg), name)
+	benchmark.Results = append(benchmark.Results, result)
+	return nil
+}
+
+func ensureBenchmark(pkgObj *Package, name string) *Benchmark {
+	for _, benchmark := range pkgObj.Benchmarks {
+		if benchmark.Name == name {
+			return benchmark
+		}
+	}
+	benchmark := &Benchmark{
+		Name: name,
+	}
+	pkgObj.Benchmarks = append(pkgObj.Benchmarks, benchmark)
+	return benchmark
+}
+
 func (p *packageTracker) ensureTest(pkgObj *Package, test string) *TestCase {
 	if _, ok := pkgObj.TestCasesByName[test]; !ok {
 		tc := &TestCase{
@@ -216,9 +403,6 @@ func (p *packageTracker) ensureTest(pkgObj *Package, test string) *TestCase {
 }
 
 func (p *packageTracker) ensurePackage(pkg string) *Package {
-	if pkg == "" {
-		panic("BUG: Empty package name encountered.")
-	}
 	if _, ok := p.packagesByName[pkg]; !ok {
 		pkgObj := &Package{
 			StartTime:       nil,
@@ -277,9 +461,38 @@ func (p *packageTracker) SetResult(pkg string, test string, result Result) {
 		return
 	}
 	testCase := p.ensureTest(pkgObj, test)
+	if testCase.Result != "" && testCase.Fuzz == nil {
+		// Without the run lines (no -v) a second result is the only sign of a new attempt. A fuzz test reports the
+		// run of the failing input as a second result with the same name, which is not a new attempt.
+		startAttempt(testCase)
+	}
 	testCase.Result = result
 }
 
+// StartAttempt records a new run of a test case. If the test case already has a result, the previous run is stored as
+// an attempt.
+func (p *packageTracker) StartAttempt(pkg string, test string) {
+	if pkg == "" || test == "" {
+		return
+	}
+	testCase := p.ensureTest(p.ensurePackage(pkg), test)
+	if testCase.Result != "" {
+		startAttempt(testCase)
+	}
+}
+
+// startAttempt moves the result of the current run of the test case into the attempts.
+func startAttempt(testCase *TestCase) {
+	testCase.Attempts = append(testCase.Attempts, TestAttempt{
+		Result:   testCase.Result,
+		Duration: testCase.Duration,
+		Output:   strings.TrimRight(testCase.Output, "\n"),
+	})
+	testCase.Result = ""
+	testCase.Duration = 0
+	testCase.Output = ""
+}
+
 func (p *packageTracker) SetCoverage(pkg string, test string, coverage float64) {
 	if pkg == "" {
 		return
@@ -293,6 +506,13 @@ func (p *packageTracker) SetCoverage(pkg string, test string, coverage float64)
 	testCase.Coverage = &coverage
 }
 
+func (p *packageTracker) SetNoStatements(pkg string) {
+	if pkg == "" {
+		return
+	}
+	p.ensurePackage(pkg).NoStatements = true
+}
+
 func (p *packageTracker) AddReason(pkg string, reason string) {
 	if pkg == "" {
 		return
@@ -314,6 +534,25 @@ func (p *packageTracker) SetCached(pkg string, test string) {
 	testCase.Cached = true
 }
 
+// WritePackage finalizes a single package and sends it to the target. Any later events for the same package will
+// start a new package object.
+func (p *packageTracker) WritePackage(pkg string) {
+	pkgObj, ok := p.packagesByName[pkg]
+	if !ok {
+		return
+	}
+	delete(p.packagesByName, pkg)
+	for i, remainingPkg := range p.packages {
+		if remainingPkg == pkgObj {
+			p.packages = append(p.packages[:i], p.packages[i+1:]...)
+			break
+		}
+	}
+	p.finalize(pkgObj)
+	p.target <- pkgObj
+}
+
+// Write sends all packages that have not been written yet sorted by name.
 func (p *packageTracker) Write() {
 	sort.SliceStable(
 		p.packages, func(i, j int) bool {
@@ -321,23 +560,265 @@ func (p *packageTracker) Write() {
 		},
 	)
 	for _, pkg := range p.packages {
-		pkg.Output = strings.TrimRight(pkg.Output, "\n")
-		pkg.Reason = strings.TrimRight(pkg.Reason, "\n")
-		sort.SliceStable(
-			pkg.TestCases, func(i, j int) bool {
-				return compareTestCaseNames(pkg.TestCases[i].Name, pkg.TestCases[j].Name)
-			},
-		)
-		for _, tc := range pkg.TestCases {
-			tc.Output = strings.TrimRight(tc.Output, "\n")
-			if tc.Result == "" {
-				tc.Result = ResultFail
-			}
-		}
+		p.finalize(pkg)
 	}
 	for _, pkg := range p.packages {
 		p.target <- pkg
 	}
+	p.packages = nil
+	p.packagesByName = map[string]*Package{}
+}
+
+func (p *packageTracker) finalize(pkg *Package) {
+	pkg.Output = strings.TrimRight(pkg.Output, "\n")
+	pkg.Reason = strings.TrimRight(pkg.Reason, "\n")
+	finalizeBenchmarks(pkg)
+	sort.SliceStable(
+		pkg.TestCases, func(i, j int) bool {
+			return compareTestCaseNames(pkg.TestCases[i].Name, pkg.TestCases[j].Name)
+		},
+	)
+	pkg.linkTestCases()
+	var unfinished []string
+	for _, tc := range pkg.TestCases {
+		tc.Output = strings.TrimRight(tc.Output, "\n")
+		if tc.Result == "" {
+			unfinished = append(unfinished, tc.Name)
+			tc.Result = ResultFail
+		}
+	}
+	p.finalizeRaces(pkg)
+	p.finalizePanics(pkg, unfinished)
+	for _, tc := range pkg.TestCases {
+		p.finalizeAttempts(pkg.Name, tc)
+		tc.Failures = p.locations.parseAssertions(pkg.Name, tc.Output)
+		tc.FailingInput = failingInput(tc)
+	}
+	for _, tc := range pkg.TestCases {
+		// Tests failing because of a failed subtest are annotated on the subtest.
+		if tc.Result != ResultFail || hasFailedSubtest(pkg, tc) {
+			continue
+		}
+		if tc.Panic != nil {
+			if origin := tc.Panic.Origin(); origin != nil {
+				tc.Locations = []Location{
+					{
+						File:    origin.File,
+						Line:    origin.Line,
+						Message: "panic: " + tc.Panic.Message,
+					},
+				}
+				continue
+			}
+		}
+		if len(tc.Races) > 0 && len(tc.Races[0].Accesses) > 0 {
+			if origin := tc.Races[0].Accesses[0].Origin(); origin != nil {
+				tc.Locations = []Location{
+					{
+						File:    origin.File,
+						Line:    origin.Line,
+						Message: "data race: " + tc.Races[0].Description(),
+					},
+				}
+				continue
+			}
+		}
+		tc.Locations = p.locations.extract(pkg.Name, failureOutput(tc))
+	}
+}
+
+// finalizeRaces moves the race reports from the output of the test cases and the package into structured races.
+func (p *packageTracker) finalizeRaces(pkg *Package) {
+	for _, tc := range pkg.TestCases {
+		tc.Output, tc.Races = p.locations.extractRaces(pkg.Name, tc.Output)
+	}
+	pkg.Output, pkg.Races = p.locations.extractRaces(pkg.Name, pkg.Output)
+}
+
+// finalizePanics moves panics to the test case that panicked. go test reports the panic output for the test it
+// considers running, or for the package, which is often a parent of the test that actually panicked. Panics caused by
+// the -timeout flag are not attributed to a test, they mark the package and the unfinished tests as timed out instead.
+func (p *packageTracker) finalizePanics(pkg *Package, unfinished []string) {
+	for _, tc := range pkg.TestCases {
+		if tc.Result == ResultPass || tc.Result == ResultSkip {
+			// Tests that did not fail can only have logged a line looking like a panic.
+			continue
+		}
+		output, panicOutput, panicInfo := p.locations.extractPanic(pkg.Name, tc.Output)
+		if panicInfo == nil {
+			continue
+		}
+		if timeout := parseTimeout(panicInfo); timeout != nil {
+			setTimeout(pkg, timeout, panicOutput, unfinished)
+			continue
+		}
+		if tc.Result != ResultFail {
+			continue
+		}
+		if panicking := panickingTest(pkg, tc); panicking != nil {
+			tc.Output = output
+			addPanic(panicking, panicOutput, panicInfo)
+		}
+	}
+	output, panicOutput, panicInfo := p.locations.extractPanic(pkg.Name, pkg.Output)
+	if panicInfo == nil {
+		return
+	}
+	if timeout := parseTimeout(panicInfo); timeout != nil {
+		setTimeout(pkg, timeout, panicOutput, unfinished)
+		return
+	}
+	if tc := panickingTest(pkg, nil); tc != nil {
+		pkg.Output = output
+		addPanic(tc, panicOutput, panicInfo)
+	}
+}
+
+func addPanic(tc *TestCase, panicOutput string, panicInfo *Panic) {
+	if tc.Output != "" {
+		tc.Output += "\n"
+	}
+	tc.Output += panicOutput
+	tc.Panic = panicInfo
+}
+
+// panickingTest returns the failed test case that started last among the given test case and its subtests, or among
+// all test cases in the package if the test case is nil. When a test panics, it and all running parent tests fail, and
+// no further tests are started.
+func panickingTest(pkg *Package, parent *TestCase) *TestCase {
+	var result *TestCase
+	for _, tc := range pkg.TestCases {
+		if tc.Result != ResultFail {
+			continue
+		}
+		if parent != nil && tc != parent && !strings.HasPrefix(tc.Name, parent.Name+"/") {
+			continue
+		}
+		if result == nil || startedAfter(tc, result) {
+			result = tc
+		}
+	}
+	return result
+}
+
+// startedAfter returns true if the first test case started after the second one, or at the same time and is nested
+// deeper.
+func startedAfter(tc1 *TestCase, tc2 *TestCase) bool {
+	switch {
+	case tc1.StartTime == nil:
+		return false
+	case tc2.StartTime == nil:
+		return true
+	case tc1.StartTime.Equal(*tc2.StartTime):
+		return strings.Count(tc1.Name, "/") > strings.Count(tc2.Name, "/")
+	default:
+		return tc1.StartTime.After(*tc2.StartTime)
+	}
+}
+
+// finalizeBenchmarks moves the benchmarks out of the test cases. Benchmarks do not receive a result unless they fail or
+// are skipped, so a benchmark without a result is only kept as a failed test case if the package failed without any
+// results from it.
+func finalizeBenchmarks(pkg *Package) {
+	testCases := pkg.TestCases[:0]
+	for _, tc := range pkg.TestCases {
+		if !strings.HasPrefix(tc.Name, "Benchmark") || tc.Result != "" ||
+			(pkg.Result == ResultFail && !hasBenchmarkResults(pkg, tc.Name)) {
+			testCases = append(testCases, tc)
+			continue
+		}
+		delete(pkg.TestCasesByName, tc.Name)
+		if output := strings.TrimRight(tc.Output, "\n"); output != "" {
+			ensureBenchmark(pkg, tc.Name).Output = output
+		}
+	}
+	pkg.TestCases = testCases
+	sort.SliceStable(
+		pkg.Benchmarks, func(i, j int) bool {
+			return compareTestCaseNames(pkg.Benchmarks[i].Name, pkg.Benchmarks[j].Name)
+		},
+	)
+}
+
+// hasBenchmarkResults returns true if the benchmark or one of its sub-benchmarks reported a result.
+func hasBenchmarkResults(pkg *Package, name string) bool {
+	for _, benchmark := range pkg.Benchmarks {
+		if (benchmark.Name == name || strings.HasPrefix(benchmark.Name, name+"/")) && len(benchmark.Results) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// finalizeAttempts combines the attempts of a test case in the current package run, adds the attempts from earlier runs
+// of the same package and determines if the test case is flaky.
+func (p *packageTracker) finalizeAttempts(pkg string, tc *TestCase) {
+	previousAttempts, ok := p.previousAttempts[pkg]
+	if !ok {
+		previousAttempts = map[string][]TestAttempt{}
+		p.previousAttempts[pkg] = previousAttempts
+	}
+	if len(tc.Attempts) > 0 {
+		startAttempt(tc)
+		outputs := make([]string, 0, len(tc.Attempts))
+		for _, attempt := range tc.Attempts {
+			tc.Duration += attempt.Duration
+			if attempt.Output != "" {
+				outputs = append(outputs, attempt.Output)
+			}
+			// A test fails if any of its attempts in this run fails, just like go test does.
+			if tc.Result != ResultFail {
+				tc.Result = attempt.Result
+			}
+		}
+		tc.Output = strings.Join(outputs, "\n")
+	}
+	attempts := tc.Attempts
+	if len(attempts) == 0 {
+		attempts = []TestAttempt{
+			{
+				Result:   tc.Result,
+				Duration: tc.Duration,
+				Output:   tc.Output,
+			},
+		}
+	}
+	attempts = append(append([]TestAttempt(nil), previousAttempts[tc.Name]...), attempts...)
+	previousAttempts[tc.Name] = attempts
+	if len(attempts) < 2 {
+		return
+	}
+	tc.Attempts = attempts
+	passed := false
+	failed := false
+	for _, attempt := range attempts {
+		switch attempt.Result {
+		case ResultPass:
+			passed = true
+		case ResultFail:
+			failed = true
+		}
+	}
+	tc.Flaky = passed && failed
+}
+
+// failureOutput returns the output of the last failed attempt of a test case.
+func failureOutput(tc *TestCase) string {
+	for i := len(tc.Attempts) - 1; i >= 0; i-- {
+		if tc.Attempts[i].Result == ResultFail {
+			return tc.Attempts[i].Output
+		}
+	}
+	return tc.Output
+}
+
+func hasFailedSubtest(pkg *Package, testCase *TestCase) bool {
+	for _, tc := range pkg.TestCases {
+		if tc.Result == ResultFail && strings.HasPrefix(tc.Name, testCase.Name+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 func compareTestCaseNames(name1 string, name2 string) bool {
@@ -369,16 +850,17 @@ type downloadsTracker struct {
 	failureReason          []byte
 }
 
-func (d *downloadsTracker) Add(name string, version string) {
+func (d *downloadsTracker) Add(name string, version string, received time.Time) error {
 	if d.downloadsFinished {
-		panic(fmt.Errorf("tried to add download after downloads are already finished (%v)", name))
+		return fmt.Errorf("tried to add download after downloads are already finished (%v)", name)
 	}
 
-	pkg := d.ensurePackage(name)
+	pkg := d.ensurePackage(name, received)
 	if version != "" {
 		pkg.Version = version
 	}
 	d.lastDownload = pkg
+	return nil
 }
 
 func (d *downloadsTracker) GetLast() *Download {
@@ -413,23 +895,25 @@ func (d *downloadsTracker) Write() {
 	close(d.target)
 }
 
-func (d *downloadsTracker) SetDownloadFailed(name string, version string) {
+func (d *downloadsTracker) SetDownloadFailed(name string, version string, received time.Time) error {
 	if d.downloadsFinished {
-		panic(fmt.Errorf("tried to add download after downloads are already finished (%v)", name))
+		return fmt.Errorf("tried to add download after downloads are already finished (%v)", name)
 	}
-	pkg := d.ensurePackage(name)
+	pkg := d.ensurePackage(name, received)
 	if version != "" {
 		pkg.Version = version
 	}
 	pkg.Failed = true
+	return nil
 }
 
-func (d *downloadsTracker) ensurePackage(name string) *Download {
-	t := time.Now()
+// ensurePackage returns the download of the package, creating it if needed. The time the event was received extends the
+// time span of the downloads.
+func (d *downloadsTracker) ensurePackage(name string, received time.Time) *Download {
 	if d.startTime == nil {
-		d.startTime = &t
+		d.startTime = &received
 	}
-	d.endTime = &t
+	d.endTime = &received
 	if _, ok := d.downloadsByPackage[name]; !ok {
 		d.downloadsByPackage[name] = &Download{
 			Package: name,
@@ -439,17 +923,19 @@ func (d *downloadsTracker) ensurePackage(name string) *Download {
 	return d.downloadsByPackage[name]
 }
 
-func (d *downloadsTracker) AddReason(name string, output []byte) {
+func (d *downloadsTracker) AddReason(name string, output []byte, received time.Time) error {
 	if d.downloadsFinished {
-		panic(fmt.Errorf("tried to add download after downloads are already finished (%v)", name))
+		return fmt.Errorf("tried to add download after downloads are already finished (%v)", name)
 	}
-	pkg := d.ensurePackage(name)
+	pkg := d.ensurePackage(name, received)
 	pkg.Reason = pkg.Reason + string(output) + "\n"
+	return nil
 }
 
-func (d *downloadsTracker) SetFailureReason(output []byte) {
+func (d *downloadsTracker) SetFailureReason(output []byte) error {
 	if d.downloadsFinished {
-		panic(fmt.Errorf("tried to add download failure reason after downloads are already finished"))
+		return fmt.Errorf("tried to add download failure reason after downloads are already finished")
 	}
 	d.failureReason = append(append(d.failureReason, output...), '\n')
+	return nil
 }
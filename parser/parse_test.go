@@ -1,22 +1,32 @@
 package parser_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/fs"
 	"os"
 	path2 "path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gotesttools/gotestfmt/v2/parser"
 	"github.com/gotesttools/gotestfmt/v2/testutil"
 	"github.com/gotesttools/gotestfmt/v2/tokenizer"
 )
 
+// testWorkDir is the directory the test sources were in when the fixtures in ../testdata were recorded. Source
+// locations are resolved relative to it, so the results do not depend on where the repository is checked out.
+const testWorkDir = "/home/runner/work/example"
+
 // TestParse takes the *.tokenizer.json and *.parser.json files in ../testdata, runs the tokenizer files as input
 // through the parser and compares the result with the parser files.
 func TestParse(t *testing.T) {
+	defer parser.SetWorkDir(testWorkDir)()
+
 	t.Logf("Locating testdata directory...")
 	tryDirectories := []string{
 		"./testdata",
@@ -97,6 +107,10 @@ func TestParse(t *testing.T) {
 				}
 				close(parserInput)
 				<-readerDone
+				// Packages are streamed in the order they finish, the expectations are stored sorted by name.
+				sort.SliceStable(parserResult.Packages, func(i, j int) bool {
+					return parserResult.Packages[i].Name < parserResult.Packages[j].Name
+				})
 
 				var expectedOutput parser.ParseResult
 				expectedFh, err := os.Open(expectedFile)
@@ -127,6 +141,18 @@ func TestParse(t *testing.T) {
 				if diff != "" {
 					t.Fatalf("The expected output did not match the real output:\n%v", diff)
 				}
+
+				report := &bytes.Buffer{}
+				if err := parser.WriteJSONReport(report, &parserResult); err != nil {
+					t.Fatalf("Failed to write JSON report (%v)", err)
+				}
+				readBack, err := parser.ReadJSONReport(report)
+				if err != nil {
+					t.Fatalf("Failed to read JSON report (%v)", err)
+				}
+				if diff := testutil.Diff(parserResult, *readBack); diff != "" {
+					t.Fatalf("The JSON report did not round-trip:\n%v", diff)
+				}
 				t.Logf("No difference, test successful.")
 			},
 		)
@@ -135,3 +161,167 @@ func TestParse(t *testing.T) {
 		t.Fatal(e)
 	}
 }
+
+// TestReadJSONReportSchemaVersion checks that reports written by a newer version of gotestfmt are rejected.
+func TestReadJSONReportSchemaVersion(t *testing.T) {
+	if _, err := parser.ReadJSONReport(strings.NewReader(`{"schemaVersion":1,"packages":[]}`)); err != nil {
+		t.Fatalf("Failed to read a report with the current schema version (%v)", err)
+	}
+	newer := fmt.Sprintf(`{"schemaVersion":%d,"packages":[]}`, parser.SchemaVersion+1)
+	if _, err := parser.ReadJSONReport(strings.NewReader(newer)); err == nil {
+		t.Fatalf("Reading a report with a newer schema version did not fail.")
+	}
+}
+
+// TestParseTextBenchmarks checks that benchmark results without -json are kept as plain output, because they cannot be
+// attributed to a package.
+func TestParseTextBenchmarks(t *testing.T) {
+	input := strings.Join([]string{
+		"goos: linux",
+		"pkg: example.com/bench",
+		"BenchmarkConcat-8 \t    1000\t       179.4 ns/op",
+		"PASS",
+		"ok  \texample.com/bench\t0.006s",
+	}, "\n")
+	prefixes, downloads, packages := parser.Parse(tokenizer.Tokenize(strings.NewReader(input)))
+	var prefix []string
+	for {
+		line, ok := <-prefixes
+		if !ok {
+			break
+		}
+		prefix = append(prefix, line)
+	}
+	for {
+		if _, ok := <-downloads; !ok {
+			break
+		}
+	}
+	var result []*parser.Package
+	for {
+		pkg, ok := <-packages
+		if !ok {
+			break
+		}
+		result = append(result, pkg)
+	}
+	if len(result) != 1 || result[0].Name != "example.com/bench" || result[0].Result != parser.ResultPass {
+		t.Fatalf("unexpected packages: %v", result)
+	}
+	if len(result[0].Benchmarks) != 0 {
+		t.Fatalf("benchmark results without -json were attributed to a package: %v", result[0].Benchmarks)
+	}
+	found := false
+	for _, line := range prefix {
+		if strings.HasPrefix(line, "BenchmarkConcat-8") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("the benchmark result is missing from the text before the tests: %v", prefix)
+	}
+}
+
+// TestParseStreamsPackages checks that a package is sent as soon as its result has been seen, before the input ends.
+func TestParseStreamsPackages(t *testing.T) {
+	input := make(chan tokenizer.Event)
+	prefixes, downloads, packages := parser.Parse(input)
+	go func() {
+		for _, evt := range []tokenizer.Event{
+			{Action: tokenizer.ActionRun, Package: "example.com/a", Test: "TestA", JSON: true},
+			{Action: tokenizer.ActionPass, Package: "example.com/a", Test: "TestA", JSON: true},
+			{Action: tokenizer.ActionPass, Package: "example.com/a", JSON: true},
+			{Action: tokenizer.ActionRun, Package: "example.com/b", Test: "TestB", JSON: true},
+		} {
+			input <- evt
+		}
+	}()
+	for {
+		if _, ok := <-prefixes; !ok {
+			break
+		}
+	}
+	for {
+		if _, ok := <-downloads; !ok {
+			break
+		}
+	}
+	select {
+	case pkg := <-packages:
+		if pkg == nil || pkg.Name != "example.com/a" || pkg.Result != parser.ResultPass {
+			t.Fatalf("unexpected first package: %v", pkg)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the finished package was not sent before the end of the input")
+	}
+	go func() {
+		for _, evt := range []tokenizer.Event{
+			{Action: tokenizer.ActionPass, Package: "example.com/b", Test: "TestB", JSON: true},
+			{Action: tokenizer.ActionPass, Package: "example.com/b", JSON: true},
+		} {
+			input <- evt
+		}
+		close(input)
+	}()
+	var rest []string
+	for {
+		pkg, ok := <-packages
+		if !ok {
+			break
+		}
+		rest = append(rest, pkg.Name)
+	}
+	if len(rest) != 1 || rest[0] != "example.com/b" {
+		t.Fatalf("unexpected remaining packages: %v", rest)
+	}
+}
+
+// TestJSONReportTimes checks that the start and end times of the downloads, packages and test cases are kept in the
+// JSON report.
+func TestJSONReportTimes(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(3 * time.Second)
+	testStart := start.Add(4 * time.Second)
+	result := &parser.ParseResult{
+		Downloads: parser.Downloads{
+			Packages:  []*parser.Download{{Package: "example.com/dep", Version: "v1.0.0"}},
+			StartTime: &start,
+			EndTime:   &end,
+		},
+		Packages: []parser.Package{
+			{
+				Name:      "example.com/a",
+				Result:    parser.ResultPass,
+				StartTime: &testStart,
+				TestCases: []*parser.TestCase{
+					{Name: "TestA", Result: parser.ResultPass, StartTime: &testStart},
+				},
+			},
+		},
+	}
+	report := &bytes.Buffer{}
+	if err := parser.WriteJSONReport(report, result); err != nil {
+		t.Fatalf("Failed to write JSON report (%v)", err)
+	}
+	readBack, err := parser.ReadJSONReport(report)
+	if err != nil {
+		t.Fatalf("Failed to read JSON report (%v)", err)
+	}
+	downloads := readBack.Downloads
+	if downloads.StartTime == nil || !downloads.StartTime.Equal(start) {
+		t.Fatalf("unexpected download start time: %v (expected: %v)", downloads.StartTime, start)
+	}
+	if downloads.EndTime == nil || !downloads.EndTime.Equal(end) {
+		t.Fatalf("unexpected download end time: %v (expected: %v)", downloads.EndTime, end)
+	}
+	pkg := readBack.Packages[0]
+	if pkg.StartTime == nil || !pkg.StartTime.Equal(testStart) {
+		t.Fatalf("unexpected package start time: %v (expected: %v)", pkg.StartTime, testStart)
+	}
+	if tc := pkg.TestCases[0]; tc.StartTime == nil || !tc.StartTime.Equal(testStart) {
+		t.Fatalf("unexpected test case start time: %v (expected: %v)", tc.StartTime, testStart)
+	}
+	if diff := testutil.Diff(*result, *readBack); diff != "" {
+		t.Fatalf("The JSON report did not round-trip:\n%v", diff)
+	}
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// raceSeparator is the line the race detector prints before and after each race report.
+const raceSeparator = "=================="
+
+// raceAccessRegexp matches the header of a memory access in a race report, such as
+// "Previous write at 0x00c0000182a8 by goroutine 8:". Accesses by threads not started by Go, such as "by thread T1", have
+// no goroutine ID.
+var raceAccessRegexp = regexp.MustCompile(
+	`^(?P<Previous>Previous )?(?P<Kind>(?:[Aa]tomic )?(?:[Rr]ead|[Ww]rite)) at (?P<Address>0x[0-9a-f]+) by (?:goroutine (?P<Goroutine>[0-9]+)|(?P<Main>main goroutine)|thread T[0-9]+):$`,
+)
+
+// raceGoroutineRegexp matches the header of a goroutine creation site in a race report, such as
+// "Goroutine 9 (running) created at:".
+var raceGoroutineRegexp = regexp.MustCompile(`^Goroutine (?P<ID>[0-9]+) \((?P<State>[^)]*)\) created at:$`)
+
+// raceFrameFileRegexp matches the source location of a stack frame in a race report, such as
+// "      /path/to/foo.go:10 +0x36".
+var raceFrameFileRegexp = regexp.MustCompile(`^\s+(?P<File>[^\s]+\.go):(?P<Line>[0-9]+)(?: \+0x[0-9a-f]+)?$`)
+
+// extractRaces removes the race reports from the output of a test case or package in the given package. It returns the
+// remaining output and the parsed races.
+func (r *locationResolver) extractRaces(pkg string, output string) (string, []DataRace) {
+	lines := strings.Split(output, "\n")
+	var remaining []string
+	var races []DataRace
+	for i := 0; i < len(lines); i++ {
+		if lines[i] != raceSeparator || i+1 >= len(lines) || lines[i+1] != "WARNING: DATA RACE" {
+			remaining = append(remaining, lines[i])
+			continue
+		}
+		end := i + 2
+		for end < len(lines) && lines[end] != raceSeparator {
+			end++
+		}
+		races = append(races, r.parseRace(pkg, lines[i+2:end]))
+		i = end
+	}
+	if races == nil {
+		return output, nil
+	}
+	return strings.TrimRight(strings.Join(remaining, "\n"), "\n"), races
+}
+
+// parseRace parses the lines of a race report between the "WARNING: DATA RACE" line and the closing separator. The
+// accesses are empty if the report is truncated or none of the access headers are recognized.
+func (r *locationResolver) parseRace(pkg string, lines []string) DataRace {
+	race := DataRace{
+		Output: strings.TrimRight(strings.Join(append([]string{"WARNING: DATA RACE"}, lines...), "\n"), "\n"),
+	}
+	for i := 0; i < len(lines); i++ {
+		if match := raceAccessRegexp.FindStringSubmatch(lines[i]); len(match) != 0 {
+			access := RaceAccess{
+				Kind:     strings.ToLower(match[2]),
+				Previous: match[1] != "",
+				Address:  match[3],
+			}
+			if match[5] != "" {
+				access.Goroutine = 1
+			} else if goroutine, err := strconv.Atoi(match[4]); err == nil {
+				access.Goroutine = goroutine
+			}
+			access.Stack, i = r.parseRaceFrames(pkg, lines, i+1)
+			race.Accesses = append(race.Accesses, access)
+		} else if match := raceGoroutineRegexp.FindStringSubmatch(lines[i]); len(match) != 0 {
+			goroutine := RaceGoroutine{
+				State: match[2],
+			}
+			if id, err := strconv.Atoi(match[1]); err == nil {
+				goroutine.ID = id
+			}
+			goroutine.Stack, i = r.parseRaceFrames(pkg, lines, i+1)
+			race.Goroutines = append(race.Goroutines, goroutine)
+		}
+	}
+	return race
+}
+
+// parseRaceFrames parses the stack frames starting at the given line until the first line that is not part of a
+// frame. It returns the frames and the index of the last line consumed.
+func (r *locationResolver) parseRaceFrames(pkg string, lines []string, start int) ([]StackFrame, int) {
+	var frames []StackFrame
+	i := start
+	for ; i+1 < len(lines); i += 2 {
+		match := raceFrameFileRegexp.FindStringSubmatch(lines[i+1])
+		if len(match) == 0 || strings.TrimSpace(lines[i]) == "" {
+			break
+		}
+		lineNumber, err := strconv.Atoi(match[2])
+		if err != nil {
+			break
+		}
+		frames = append(frames, StackFrame{
+			Function: stackFrameFunction(strings.TrimSpace(lines[i])),
+			File:     r.resolve(pkg, match[1]),
+			Line:     lineNumber,
+		})
+	}
+	return frames, i - 1
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timeoutRegexp matches the panic message of a test binary exceeding the -timeout flag.
+var timeoutRegexp = regexp.MustCompile(`^test timed out after (?P<After>[^\s]+)$`)
+
+// runningTestRegexp matches an entry in the list of running tests printed after the timeout panic by newer Go
+// versions, such as "		TestFoo (10m0s)".
+var runningTestRegexp = regexp.MustCompile(`^\t\t(?P<Test>[^\s]+) \([^)]*\)$`)
+
+// parseTimeout returns the timeout if the panic was caused by the -timeout flag, or nil otherwise.
+func parseTimeout(panicInfo *Panic) *Timeout {
+	match := timeoutRegexp.FindStringSubmatch(panicInfo.Message)
+	if len(match) == 0 {
+		return nil
+	}
+	after, err := time.ParseDuration(match[1])
+	if err != nil {
+		return nil
+	}
+	return &Timeout{
+		After: after,
+	}
+}
+
+// runningTests returns the test names from the "running tests:" block following the timeout panic.
+func runningTests(panicOutput string) []string {
+	lines := strings.Split(panicOutput, "\n")
+	var tests []string
+	for i, line := range lines {
+		if line != "\trunning tests:" {
+			continue
+		}
+		for _, testLine := range lines[i+1:] {
+			match := runningTestRegexp.FindStringSubmatch(testLine)
+			if len(match) == 0 {
+				break
+			}
+			tests = append(tests, match[1])
+		}
+		break
+	}
+	return tests
+}
+
+// setTimeout marks the package as timed out. The running tests are taken from the list printed by go test and from the
+// test cases that never received a result.
+func setTimeout(pkg *Package, timeout *Timeout, panicOutput string, unfinished []string) {
+	seen := map[string]bool{}
+	for _, test := range append(runningTests(panicOutput), unfinished...) {
+		if seen[test] {
+			continue
+		}
+		seen[test] = true
+		timeout.RunningTests = append(timeout.RunningTests, test)
+		if tc, ok := pkg.TestCasesByName[test]; ok {
+			tc.TimedOut = true
+		}
+	}
+	pkg.Timeout = timeout
+	if pkg.Reason == "" {
+		pkg.Reason = "test timed out after " + timeout.After.String()
+	}
+}
@@ -0,0 +1,59 @@
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// formatBenchmarks renders the benchmark results as a table with aligned columns, one line per result. Each line is
+// prefixed with the indent. Benchmarks without results are left out.
+func formatBenchmarks(indent string, benchmarks []*parser.Benchmark) (string, error) {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	for _, benchmark := range benchmarks {
+		for _, result := range benchmark.Results {
+			name := benchmark.Name
+			if result.Procs > 0 {
+				name = fmt.Sprintf("%s-%d", name, result.Procs)
+			}
+			cells := []string{
+				indent + name,
+				strconv.FormatInt(result.Iterations, 10),
+				formatBenchmarkValue(result.NsPerOp) + " ns/op",
+				"",
+				"",
+			}
+			if result.BytesPerOp != nil {
+				cells[3] = strconv.FormatInt(*result.BytesPerOp, 10) + " B/op"
+			}
+			if result.AllocsPerOp != nil {
+				cells[4] = strconv.FormatInt(*result.AllocsPerOp, 10) + " allocs/op"
+			}
+			units := make([]string, 0, len(result.Metrics))
+			for unit := range result.Metrics {
+				units = append(units, unit)
+			}
+			sort.Strings(units)
+			for _, unit := range units {
+				cells = append(cells, formatBenchmarkValue(result.Metrics[unit])+" "+unit)
+			}
+			if _, err := fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, "\t"), "\t")); err != nil {
+				return "", fmt.Errorf("failed to format benchmark %s (%w)", benchmark.Name, err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", fmt.Errorf("failed to format benchmarks (%w)", err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+func formatBenchmarkValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
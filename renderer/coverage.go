@@ -0,0 +1,128 @@
+package renderer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// CoverageThreshold is the minimum coverage required for the packages matching a pattern.
+type CoverageThreshold struct {
+	// Pattern is a package pattern, such as "example.com/foo" or "example.com/foo/...". Patterns without a domain, such
+	// as "internal/...", are relative to the module root. An empty pattern matches all packages.
+	Pattern string
+	// MinCoverage is the minimum coverage percentage.
+	MinCoverage float64
+}
+
+// ParseCoverageThreshold parses a threshold in the format of the -min-coverage flag, either a percentage for all
+// packages, such as "70", or a pattern and a percentage, such as "internal/...=85".
+func ParseCoverageThreshold(value string) (CoverageThreshold, error) {
+	threshold := CoverageThreshold{}
+	percentage := value
+	if i := strings.LastIndex(value, "="); i >= 0 {
+		threshold.Pattern = strings.TrimSpace(value[:i])
+		percentage = value[i+1:]
+		if threshold.Pattern == "" {
+			return threshold, fmt.Errorf("missing package pattern in coverage threshold: %s", value)
+		}
+	}
+	minCoverage, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(percentage), "%"), 64)
+	if err != nil {
+		return threshold, fmt.Errorf("invalid coverage threshold: %s (%w)", value, err)
+	}
+	if minCoverage < 0 || minCoverage > 100 {
+		return threshold, fmt.Errorf("coverage threshold out of range: %s (must be between 0 and 100)", value)
+	}
+	threshold.MinCoverage = minCoverage
+	return threshold, nil
+}
+
+// Matches returns true if the pattern of the threshold matches the package name. Relative patterns are resolved
+// against the module path. If the module path is empty they are matched as they are.
+func (t CoverageThreshold) Matches(modulePath string, pkg string) bool {
+	pattern := t.Pattern
+	if modulePath != "" {
+		switch {
+		case pattern == ".":
+			pattern = modulePath
+		case strings.HasPrefix(pattern, "./"):
+			pattern = modulePath + pattern[1:]
+		case pattern != "" && pattern != "..." && !isAbsolutePattern(pattern):
+			pattern = modulePath + "/" + pattern
+		}
+	}
+	pattern = strings.TrimPrefix(pattern, "./")
+	if pattern == "" || pattern == "..." {
+		return true
+	}
+	return matchPackagePattern(pattern, pkg)
+}
+
+// isAbsolutePattern returns true if the first element of the pattern contains a dot, like the domain in an import path.
+func isAbsolutePattern(pattern string) bool {
+	first := pattern
+	if i := strings.Index(pattern, "/"); i >= 0 {
+		first = pattern[:i]
+	}
+	return strings.Contains(first, ".")
+}
+
+// matchPackagePattern matches a package name against a pattern anchored at the start of the name. A pattern ending in
+// "/..." also matches the packages below it.
+func matchPackagePattern(pattern string, pkg string) bool {
+	if strings.HasSuffix(pattern, "/...") {
+		base := strings.TrimSuffix(pattern, "/...")
+		return pkg == base || strings.HasPrefix(pkg, base+"/")
+	}
+	return pkg == pattern
+}
+
+// minCoverage returns the minimum coverage for the package. Thresholds with a pattern take precedence over the global
+// threshold, and later thresholds over earlier ones. It returns nil if no threshold applies.
+func minCoverage(thresholds []CoverageThreshold, modulePath string, pkg string) *float64 {
+	var global, matched *float64
+	for i := range thresholds {
+		threshold := &thresholds[i]
+		switch {
+		case threshold.Pattern == "":
+			global = &threshold.MinCoverage
+		case threshold.Matches(modulePath, pkg):
+			matched = &threshold.MinCoverage
+		}
+	}
+	if matched != nil {
+		return matched
+	}
+	return global
+}
+
+// CoverageViolation is a package whose coverage is below the configured minimum.
+type CoverageViolation struct {
+	// Package is the name of the package.
+	Package string
+	// Coverage is the coverage percentage of the package, nil if the package did not report coverage.
+	Coverage *float64
+	// MinCoverage is the minimum coverage percentage the package violates.
+	MinCoverage float64
+}
+
+// newPackage creates the rendering context for a package and checks it against the coverage thresholds. Packages
+// without coverage data fail the check, unless they have no statements to cover.
+func newPackage(pkg *parser.Package, settings RenderSettings) Package {
+	result := Package{
+		Package:     pkg,
+		Settings:    settings,
+		MinCoverage: minCoverage(settings.CoverageThresholds, settings.ModulePath, pkg.Name),
+	}
+	if result.MinCoverage != nil {
+		if pkg.Coverage != nil {
+			result.BelowMinCoverage = *pkg.Coverage < *result.MinCoverage
+		} else {
+			result.BelowMinCoverage = !pkg.NoStatements
+		}
+	}
+	return result
+}
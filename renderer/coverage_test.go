@@ -0,0 +1,76 @@
+package renderer_test
+
+import (
+	"testing"
+
+	"github.com/gotesttools/gotestfmt/v2/renderer"
+)
+
+// TestParseCoverageThreshold checks the accepted formats of the -min-coverage flag.
+func TestParseCoverageThreshold(t *testing.T) {
+	for value, expected := range map[string]renderer.CoverageThreshold{
+		"70":                 {MinCoverage: 70},
+		"85.5%":              {MinCoverage: 85.5},
+		"internal/...=85":    {Pattern: "internal/...", MinCoverage: 85},
+		" example.com/a = 0": {Pattern: "example.com/a", MinCoverage: 0},
+		"a=b=100":            {Pattern: "a=b", MinCoverage: 100},
+	} {
+		actual, err := renderer.ParseCoverageThreshold(value)
+		if err != nil {
+			t.Fatalf("failed to parse %q (%v)", value, err)
+		}
+		if actual != expected {
+			t.Fatalf("unexpected threshold for %q: %v (expected: %v)", value, actual, expected)
+		}
+	}
+	for _, value := range []string{"", "abc", "=70", "internal/...=", "-1", "100.1", "a=101"} {
+		if _, err := renderer.ParseCoverageThreshold(value); err == nil {
+			t.Fatalf("parsing %q did not fail", value)
+		}
+	}
+}
+
+// TestCoverageThresholdMatches checks that patterns are anchored to the start of the package name and that relative
+// patterns are resolved against the module path.
+func TestCoverageThresholdMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern    string
+		modulePath string
+		pkg        string
+		expected   bool
+	}{
+		{pattern: "", pkg: "example.com/a", expected: true},
+		{pattern: "...", pkg: "example.com/a", expected: true},
+		{pattern: "example.com/a", pkg: "example.com/a", expected: true},
+		{pattern: "example.com/a", pkg: "example.com/a/b", expected: false},
+		{pattern: "example.com/a", pkg: "example.com/b/example.com/a", expected: false},
+		{pattern: "example.com/a/...", pkg: "example.com/a", expected: true},
+		{pattern: "example.com/a/...", pkg: "example.com/a/b/c", expected: true},
+		{pattern: "example.com/a/...", pkg: "example.com/ab", expected: false},
+		{pattern: "internal/...", modulePath: "example.com/mod", pkg: "example.com/mod/internal", expected: true},
+		{pattern: "internal/...", modulePath: "example.com/mod", pkg: "example.com/mod/internal/x", expected: true},
+		{pattern: "internal/...", modulePath: "example.com/mod", pkg: "example.com/mod/a/internal/x", expected: false},
+		{pattern: "./internal/...", modulePath: "example.com/mod", pkg: "example.com/mod/internal/x", expected: true},
+		{pattern: "internal", modulePath: "example.com/mod", pkg: "example.com/mod/internal", expected: true},
+		{pattern: "internal", modulePath: "example.com/mod", pkg: "example.com/mod/internal/x", expected: false},
+		{pattern: ".", modulePath: "example.com/mod", pkg: "example.com/mod", expected: true},
+		{pattern: "./...", modulePath: "example.com/mod", pkg: "example.com/mod/a", expected: true},
+		{pattern: "./...", modulePath: "example.com/mod", pkg: "example.com/other", expected: false},
+		{pattern: "internal/...", pkg: "internal/x", expected: true},
+		{pattern: "internal/...", pkg: "example.com/mod/internal/x", expected: false},
+		{pattern: "mod/a", modulePath: "mod", pkg: "mod/mod/a", expected: true},
+		{pattern: "mod/a", modulePath: "mod", pkg: "mod/a", expected: false},
+	} {
+		threshold := renderer.CoverageThreshold{Pattern: tc.pattern}
+		if actual := threshold.Matches(tc.modulePath, tc.pkg); actual != tc.expected {
+			t.Fatalf(
+				"unexpected result for pattern %q with module %q and package %q: %t (expected: %t)",
+				tc.pattern,
+				tc.modulePath,
+				tc.pkg,
+				actual,
+				tc.expected,
+			)
+		}
+	}
+}
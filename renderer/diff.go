@@ -0,0 +1,62 @@
+package renderer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// testifyLineRegexp splits a line of a testify assertion in the test output into the indentation and the content, such
+// as "        	            	+ Age: 31".
+var testifyLineRegexp = regexp.MustCompile(`^(\s*\t\s+\t)(.*)$`)
+
+// colorDiff colors the removed lines of unified diffs red, the added lines green, and the hunk headers cyan. It
+// accepts a diff on its own, such as the diff of a failed assertion, as well as a test output containing testify
+// assertions, in which case only the lines of the diffs are colored.
+func colorDiff(text string) string {
+	lines := strings.Split(text, "\n")
+	inDiff := false
+	inAssertion := false
+	for i, line := range lines {
+		prefix, content := "", line
+		if match := testifyLineRegexp.FindStringSubmatch(line); len(match) != 0 {
+			prefix, content = match[1], match[2]
+		}
+		if !inDiff {
+			if !strings.HasPrefix(content, "--- ") || i+1 >= len(lines) {
+				continue
+			}
+			next := lines[i+1]
+			if match := testifyLineRegexp.FindStringSubmatch(next); len(match) != 0 {
+				next = match[2]
+			}
+			if !strings.HasPrefix(next, "+++ ") {
+				continue
+			}
+			inDiff = true
+			inAssertion = prefix != ""
+		} else if inAssertion != (prefix != "") || (!inAssertion && !isDiffLine(content)) {
+			inDiff = false
+			continue
+		}
+		color := ""
+		switch {
+		case strings.HasPrefix(content, "-"):
+			color = "\033[0;31m"
+		case strings.HasPrefix(content, "+"):
+			color = "\033[0;32m"
+		case strings.HasPrefix(content, "@@"):
+			color = "\033[0;36m"
+		}
+		if color != "" {
+			lines[i] = prefix + color + content + "\033[0m"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isDiffLine(line string) bool {
+	return strings.HasPrefix(line, " ") ||
+		strings.HasPrefix(line, "-") ||
+		strings.HasPrefix(line, "+") ||
+		strings.HasPrefix(line, "@@")
+}
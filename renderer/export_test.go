@@ -0,0 +1,4 @@
+package renderer
+
+// EscapeTeamCity exposes escapeTeamCity to the tests.
+var EscapeTeamCity = escapeTeamCity
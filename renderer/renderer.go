@@ -5,7 +5,9 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -40,47 +42,15 @@ func RenderWithSettings(
 	packagesTemplate []byte,
 	settings RenderSettings,
 ) <-chan []byte {
-	result := make(chan []byte)
-	go func() {
-		defer close(result)
-		for {
-			prefix, ok := <-prefixes
-			if !ok {
-				break
-			}
-			result <- []byte(fmt.Sprintf("%s\n", prefix))
-		}
-
-		for {
-			downloads, ok := <-downloadsChannel
-			if !ok {
-				break
-			}
-			result <- renderTemplate(
-				"downloads.gotpl",
-				downloadsTemplate,
-				Downloads{
-					downloads,
-					settings,
-				},
-			)
-		}
-
-		for {
-			pkg, ok := <-packagesChannel
-			if !ok {
-				break
-			}
-			result <- renderTemplate(
-				"package.gotpl",
-				packagesTemplate,
-				Package{
-					pkg,
-					settings,
-				},
-			)
-		}
-	}()
+	result, _, _ := RenderWithSettingsAndErrors(
+		prefixes,
+		downloadsChannel,
+		packagesChannel,
+		downloadsTemplate,
+		packagesTemplate,
+		nil,
+		settings,
+	)
 	return result
 }
 
@@ -94,15 +64,61 @@ func RenderWithSettingsAndExitCode(
 	packagesTemplate []byte,
 	settings RenderSettings,
 ) (<-chan []byte, <-chan int) {
+	result, exitCodeChan, _ := RenderWithSettingsAndErrors(
+		prefixes,
+		downloadsChannel,
+		packagesChannel,
+		downloadsTemplate,
+		packagesTemplate,
+		nil,
+		settings,
+	)
+	return result, exitCodeChan
+}
+
+// RenderWithSettingsAndErrors takes the two input channels from the parser and renders them into text output
+// fragments as well as an exit code. If a summary template is passed, it is rendered after all packages. If a template
+// fails to render, the fragment is skipped and rendering continues with the next item. The error channel receives the
+// first such error, if any. Both the exit code and the error channel are closed after the result channel has been
+// closed.
+func RenderWithSettingsAndErrors(
+	prefixes <-chan string,
+	downloadsChannel <-chan *parser.Downloads,
+	packagesChannel <-chan *parser.Package,
+	downloadsTemplate []byte,
+	packagesTemplate []byte,
+	summaryTemplate []byte,
+	settings RenderSettings,
+) (<-chan []byte, <-chan int, <-chan error) {
+	if settings.SortPackages {
+		packagesChannel = sortPackages(packagesChannel)
+	}
 	result := make(chan []byte)
-	exitCodeChan := make(chan int)
+	exitCodeChan := make(chan int, 1)
+	errs := make(chan error, 1)
 	go func() {
-		exitCode := 0
+		exitCode := newExitCodeTracker(settings)
+		var firstErr error
 		defer func() {
 			close(result)
-			exitCodeChan <- exitCode
+			exitCodeChan <- exitCode.exitCode()
 			close(exitCodeChan)
+			if firstErr != nil {
+				errs <- firstErr
+			}
+			close(errs)
 		}()
+		render := func(templateName string, templateText []byte, data interface{}) {
+			fragment, err := renderTemplate(templateName, templateText, data)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			result <- fragment
+		}
+		summary := &summaryBuilder{}
 		for {
 			prefix, ok := <-prefixes
 			if !ok {
@@ -116,10 +132,9 @@ func RenderWithSettingsAndExitCode(
 			if !ok {
 				break
 			}
-			if downloads.Failed {
-				exitCode = 1
-			}
-			result <- renderTemplate(
+			exitCode.addDownloads(downloads)
+			summary.addDownloads(downloads)
+			render(
 				"downloads.gotpl",
 				downloadsTemplate,
 				Downloads{
@@ -134,20 +149,109 @@ func RenderWithSettingsAndExitCode(
 			if !ok {
 				break
 			}
-			if pkg.Result == parser.ResultFail {
-				exitCode = 1
-			}
-			result <- renderTemplate(
+			renderedPackage := newPackage(pkg, settings)
+			exitCode.addPackage(renderedPackage)
+			summary.addPackage(renderedPackage)
+			render(
 				"package.gotpl",
 				packagesTemplate,
-				Package{
-					pkg,
-					settings,
-				},
+				renderedPackage,
+			)
+		}
+
+		if summaryTemplate != nil {
+			render(
+				"summary.gotpl",
+				summaryTemplate,
+				summary.build(settings),
 			)
 		}
 	}()
-	return result, exitCodeChan
+	return result, exitCodeChan, errs
+}
+
+// exitCodeTracker calculates the exit code from the downloads and packages seen during rendering.
+type exitCodeTracker struct {
+	settings RenderSettings
+	failed   bool
+	// packageFailures records for the latest run of each package whether it failed for reasons other than flaky
+	// tests.
+	packageFailures map[string]bool
+}
+
+func newExitCodeTracker(settings RenderSettings) *exitCodeTracker {
+	return &exitCodeTracker{
+		settings:        settings,
+		packageFailures: map[string]bool{},
+	}
+}
+
+func (e *exitCodeTracker) addDownloads(downloads *parser.Downloads) {
+	if downloads.Failed {
+		e.failed = true
+	}
+}
+
+func (e *exitCodeTracker) addPackage(pkg Package) {
+	if (pkg.Result == parser.ResultFail && !e.settings.AllowFlaky) || pkg.BelowMinCoverage {
+		e.failed = true
+	}
+	e.packageFailures[pkg.Name] = hasNonFlakyFailure(pkg.Package)
+}
+
+func (e *exitCodeTracker) exitCode() int {
+	if e.failed {
+		return 1
+	}
+	if e.settings.AllowFlaky {
+		for _, failed := range e.packageFailures {
+			if failed {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// hasNonFlakyFailure returns true if the package failed and not all of its failed test cases are flaky.
+func hasNonFlakyFailure(pkg *parser.Package) bool {
+	if pkg.Result != parser.ResultFail {
+		return false
+	}
+	failedTests := false
+	for _, tc := range pkg.TestCases {
+		if tc.Result == parser.ResultFail {
+			if !tc.Flaky {
+				return true
+			}
+			failedTests = true
+		}
+	}
+	// Packages failing without a failed test case, e.g. because of a build error, are never flaky.
+	return !failedTests
+}
+
+// sortPackages buffers all packages until the input channel is closed and then sends them sorted by name.
+func sortPackages(packagesChannel <-chan *parser.Package) <-chan *parser.Package {
+	result := make(chan *parser.Package)
+	go func() {
+		defer close(result)
+		var packages []*parser.Package
+		for {
+			pkg, ok := <-packagesChannel
+			if !ok {
+				break
+			}
+			packages = append(packages, pkg)
+		}
+		sort.SliceStable(packages, func(i, j int) bool {
+			return packages[i].Name < packages[j].Name
+		})
+		for _, pkg := range packages {
+			result <- pkg
+		}
+	}()
+	return result
 }
 
 // Downloads contains the downloads for rendering.
@@ -162,11 +266,17 @@ type Package struct {
 	*parser.Package
 
 	Settings RenderSettings
+	// MinCoverage is the minimum coverage required for this package by the coverage thresholds in the settings, or
+	// nil if no threshold applies.
+	MinCoverage *float64
+	// BelowMinCoverage indicates that the coverage of this package is below MinCoverage, or that the package did not
+	// report coverage even though a minimum applies.
+	BelowMinCoverage bool
 }
 
-func formatTestOutput(testOutput string, cfg RenderSettings) string {
+func formatTestOutput(testOutput string, cfg RenderSettings) (string, error) {
 	if cfg.Formatter == "" {
-		return testOutput
+		return testOutput, nil
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -193,30 +303,160 @@ func formatTestOutput(testOutput string, cfg RenderSettings) string {
 	run.Stdout = stdout
 	run.Stderr = stderr
 	if err := run.Run(); err != nil {
-		panic(fmt.Errorf(
+		return "", fmt.Errorf(
 			"failed to run test output formatter '%s', stderr was: %s (%w)",
 			strings.Join(shell, " "),
 			stderr.String(),
 			err,
-		))
+		)
+	}
+	return stdout.String(), nil
+}
+
+var gitHubMessageReplacer = strings.NewReplacer(
+	"%", "%25",
+	"\r", "%0D",
+	"\n", "%0A",
+)
+
+var gitHubPropertyReplacer = strings.NewReplacer(
+	"%", "%25",
+	"\r", "%0D",
+	"\n", "%0A",
+	":", "%3A",
+	",", "%2C",
+)
+
+// escapeGitHubMessage escapes the message of a GitHub Actions workflow command, such as ::error.
+func escapeGitHubMessage(message string) string {
+	return gitHubMessageReplacer.Replace(message)
+}
+
+// escapeGitHubProperty escapes a property value of a GitHub Actions workflow command, such as the file name.
+func escapeGitHubProperty(value string) string {
+	return gitHubPropertyReplacer.Replace(value)
+}
+
+var teamCityReplacer = strings.NewReplacer(
+	"|", "||",
+	"'", "|'",
+	"\n", "|n",
+	"\r", "|r",
+	"[", "|[",
+	"]", "|]",
+	"\u0085", "|x",
+	"\u2028", "|l",
+	"\u2029", "|p",
+)
+
+// escapeTeamCity escapes a value for use in a TeamCity service message.
+func escapeTeamCity(value string) string {
+	return teamCityReplacer.Replace(value)
+}
+
+var azureMessageReplacer = strings.NewReplacer(
+	"%", "%AZP25",
+	"\r", "%0D",
+	"\n", "%0A",
+)
+
+var azurePropertyReplacer = strings.NewReplacer(
+	"%", "%AZP25",
+	"\r", "%0D",
+	"\n", "%0A",
+	"]", "%5D",
+	";", "%3B",
+)
+
+// escapeAzureMessage escapes the message of an Azure Pipelines logging command, such as ##vso[task.logissue].
+func escapeAzureMessage(message string) string {
+	return azureMessageReplacer.Replace(message)
+}
+
+// escapeAzureProperty escapes a property value of an Azure Pipelines logging command, such as the source path.
+func escapeAzureProperty(value string) string {
+	return azurePropertyReplacer.Replace(value)
+}
+
+// ansiRegexp matches ANSI escape sequences, such as colors, window titles, and cursor movements.
+var ansiRegexp = regexp.MustCompile(`\x1b(?:\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// stripANSI removes the ANSI escape sequences from the text, for example for outputs that do not support colors.
+func stripANSI(text string) string {
+	return ansiRegexp.ReplaceAllString(text, "")
+}
+
+// markdownCodeBlock returns the text without ANSI escape sequences as a fenced Markdown code block. The fence is longer
+// than the longest run of backticks in the text, so the text cannot close the block.
+func markdownCodeBlock(text string) string {
+	text = strings.TrimRight(stripANSI(text), "\n")
+	longest := 0
+	run := 0
+	for _, r := range text {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+	fence := "```"
+	if longest >= len(fence) {
+		fence = strings.Repeat("`", longest+1)
+	}
+	return fence + "\n" + text + "\n" + fence
+}
+
+// repeat returns the text repeated count times, for example to indent subtests.
+func repeat(count int, text string) string {
+	if count <= 0 {
+		return ""
+	}
+	return strings.Repeat(text, count)
+}
+
+// indent prefixes each non-empty line of the text, for example to indent the output of subtests.
+func indent(prefix string, text string) string {
+	if prefix == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
 	}
-	return stdout.String()
+	return strings.Join(lines, "\n")
 }
 
-func renderTemplate(templateName string, templateText []byte, data interface{}) []byte {
+func renderTemplate(templateName string, templateText []byte, data interface{}) ([]byte, error) {
 	result := bytes.Buffer{}
 	tpl := template.New(templateName)
 	tpl.Funcs(map[string]interface{}{
-		"formatTestOutput": formatTestOutput,
+		"formatTestOutput":     formatTestOutput,
+		"escapeGitHubMessage":  escapeGitHubMessage,
+		"escapeGitHubProperty": escapeGitHubProperty,
+		"escapeTeamCity":       escapeTeamCity,
+		"escapeAzureMessage":   escapeAzureMessage,
+		"escapeAzureProperty":  escapeAzureProperty,
+		"formatBenchmarks":     formatBenchmarks,
+		"repeat":               repeat,
+		"indent":               indent,
+		"colorDiff":            colorDiff,
+		"stripANSI":            stripANSI,
+		"markdownCodeBlock":    markdownCodeBlock,
+		"truncateOutput":       truncateOutput,
 	})
 	tpl, err := tpl.Parse(string(templateText))
 	if err != nil {
-		panic(fmt.Errorf("failed to parse template (%w)", err))
+		return nil, fmt.Errorf("failed to parse template %s (%w)", templateName, err)
 	}
 	if err := tpl.Execute(&result, data); err != nil {
-		panic(fmt.Errorf("failed to render template (%w)", err))
+		return nil, fmt.Errorf("failed to render template %s (%w)", templateName, err)
 	}
-	return result.Bytes()
+	return result.Bytes(), nil
 }
 
 // RenderSettings influence the output.
@@ -233,4 +473,30 @@ type RenderSettings struct {
 	ShowTestStatus bool
 	// Formatter is the path to an external program that is executed for each test output for format it.
 	Formatter string
+	// SortPackages buffers all packages until the test run has finished and renders them sorted by name instead of
+	// rendering each package as soon as it finishes.
+	SortPackages bool
+	// HideSummary hides the summary after all packages.
+	HideSummary bool
+	// SlowestTests is the number of slowest tests to list in the summary.
+	SlowestTests int
+	// AllowFlaky returns a zero exit code if the only failures are flaky tests, which passed in another attempt. Only
+	// the latest run of each package is considered when rerunning tests.
+	AllowFlaky bool
+	// CoverProfiles are the coverage profiles written by go test -coverprofile. They are merged and the coverage of
+	// each file is attached to the packages. Packages are only rendered once all input has been read, because go test
+	// writes the profile at the end.
+	CoverProfiles []string
+	// CoverageThresholds are the minimum coverage percentages for the packages. Packages below their threshold are
+	// marked in the output and result in a non-zero exit code.
+	CoverageThresholds []CoverageThreshold
+	// ModulePath is the path of the module under test, which the coverage threshold patterns without a domain are
+	// relative to.
+	ModulePath string
+	// MaxReportSize is the maximum size of a report rendered with RenderReport in bytes, or 0 for no limit. The test
+	// outputs are truncated to stay below it.
+	MaxReportSize int
+	// OutputFormat selects between the templates and a TAP stream. The settings to hide parts of the output only apply
+	// to the templates. Defaults to OutputFormatText.
+	OutputFormat OutputFormat
 }
@@ -0,0 +1,143 @@
+package renderer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+	"github.com/gotesttools/gotestfmt/v2/renderer"
+)
+
+// TestRenderSortPackages checks that packages are rendered in the order they arrive, unless sorting is enabled.
+func TestRenderSortPackages(t *testing.T) {
+	for sortPackages, expected := range map[bool]string{
+		false: "example.com/b\nexample.com/a\n",
+		true:  "example.com/a\nexample.com/b\n",
+	} {
+		prefixes := make(chan string)
+		close(prefixes)
+		downloads := make(chan *parser.Downloads)
+		close(downloads)
+		packages := make(chan *parser.Package, 2)
+		packages <- &parser.Package{Name: "example.com/b", Result: parser.ResultPass}
+		packages <- &parser.Package{Name: "example.com/a", Result: parser.ResultPass}
+		close(packages)
+		result := renderer.RenderWithSettings(
+			prefixes,
+			downloads,
+			packages,
+			nil,
+			[]byte("{{ .Name }}\n"),
+			renderer.RenderSettings{SortPackages: sortPackages},
+		)
+		output := &strings.Builder{}
+		for {
+			fragment, ok := <-result
+			if !ok {
+				break
+			}
+			output.Write(fragment)
+		}
+		if output.String() != expected {
+			t.Fatalf("unexpected output with sorting set to %t:\n%s\n(expected:\n%s)", sortPackages, output, expected)
+		}
+	}
+}
+
+// TestRenderSummary renders the default summary template and checks that the duration is the wall time of the test run
+// and that the coverage is weighted by the number of statements.
+func TestRenderSummary(t *testing.T) {
+	templateText, err := os.ReadFile(filepath.Join("..", ".gotestfmt", "summary.gotpl"))
+	if err != nil {
+		t.Fatalf("failed to read the summary template (%v)", err)
+	}
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	later := start.Add(time.Second)
+	firstCoverage := 80.0
+	secondCoverage := 10.0
+	output := renderSummary(t, templateText, []*parser.Package{
+		{
+			Name:      "example.com/a",
+			Result:    parser.ResultPass,
+			StartTime: &start,
+			Duration:  2 * time.Second,
+			Coverage:  &firstCoverage,
+			CoverageByFile: []parser.FileCoverage{
+				{File: "a.go", Statements: 10, CoveredStatements: 8},
+			},
+		},
+		{
+			Name:      "example.com/b",
+			Result:    parser.ResultPass,
+			StartTime: &later,
+			Duration:  3 * time.Second,
+			Coverage:  &secondCoverage,
+			CoverageByFile: []parser.FileCoverage{
+				{File: "b.go", Statements: 30, CoveredStatements: 3},
+			},
+		},
+	})
+	expected := "\033[0;32m✅ Summary\033[0m\033[0;37m (4s, 27.5% coverage)\033[0m\n" +
+		"  📦 Packages: 2 passed, 0 failed, 0 skipped\n" +
+		"  🧪 Tests: 0 passed, 0 failed, 0 skipped\n"
+	if output != expected {
+		t.Fatalf("unexpected summary:\n%q\n(expected:\n%q)", output, expected)
+	}
+}
+
+// TestRenderSummaryWithoutProfiles checks that no total coverage is shown if a package reported its coverage without a
+// coverage profile, and that packages without a start time still count towards the duration.
+func TestRenderSummaryWithoutProfiles(t *testing.T) {
+	coverage := 50.0
+	output := renderSummary(
+		t,
+		[]byte("{{ .Duration }}{{ with .Coverage }} {{ . }}%{{ end }}"),
+		[]*parser.Package{
+			{
+				Name:     "example.com/a",
+				Result:   parser.ResultPass,
+				Duration: 2 * time.Second,
+				Coverage: &coverage,
+			},
+		},
+	)
+	if output != "2s" {
+		t.Fatalf("unexpected summary: %q", output)
+	}
+}
+
+func renderSummary(t *testing.T, summaryTemplate []byte, pkgs []*parser.Package) string {
+	prefixes := make(chan string)
+	close(prefixes)
+	downloads := make(chan *parser.Downloads)
+	close(downloads)
+	packages := make(chan *parser.Package, len(pkgs))
+	for _, pkg := range pkgs {
+		packages <- pkg
+	}
+	close(packages)
+	result, _, errs := renderer.RenderWithSettingsAndErrors(
+		prefixes,
+		downloads,
+		packages,
+		nil,
+		nil,
+		summaryTemplate,
+		renderer.RenderSettings{},
+	)
+	output := &strings.Builder{}
+	for {
+		fragment, ok := <-result
+		if !ok {
+			break
+		}
+		output.Write(fragment)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("failed to render the summary (%v)", err)
+	}
+	return output.String()
+}
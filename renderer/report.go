@@ -0,0 +1,115 @@
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// minOutputLength is the length the test outputs are truncated to at most when a report exceeds its maximum size.
+const minOutputLength = 256
+
+// reportTruncatedMarker is appended to a report that exceeds its maximum size even with truncated test outputs.
+const reportTruncatedMarker = "\n\n… report truncated\n"
+
+// Report contains the complete results of a test run for rendering report files after all input has been processed.
+type Report struct {
+	// Prefix contains the text before any recognized output.
+	Prefix []string
+	// Downloads contains the dependency downloads.
+	Downloads Downloads
+	// Packages contains all packages in the order they have been received.
+	Packages []ReportPackage
+	// Summary contains the overall results.
+	Summary Summary
+	// MaxOutputLength is the length in bytes the templates should truncate test outputs to with truncateOutput, or 0
+	// if the outputs fit into the report.
+	MaxOutputLength int
+
+	Settings RenderSettings
+}
+
+// ReportPackage is a single package in a report together with the number of test cases per result.
+type ReportPackage struct {
+	Package
+
+	// Tests contains the number of test cases in this package per result, including subtests.
+	Tests ResultCounts
+}
+
+// RenderReport renders the specified template with the complete parse result. If the output is larger than the
+// MaxReportSize in the settings, the template is rendered again with shorter test outputs until it fits. If it still
+// does not fit, it is cut off.
+func RenderReport(
+	templateName string,
+	templateText []byte,
+	result *parser.ParseResult,
+	settings RenderSettings,
+) ([]byte, error) {
+	summary := &summaryBuilder{}
+	summary.addDownloads(&result.Downloads)
+	report := Report{
+		Prefix: result.Prefix,
+		Downloads: Downloads{
+			&result.Downloads,
+			settings,
+		},
+		Settings: settings,
+	}
+	for i := range result.Packages {
+		pkg := &result.Packages[i]
+		reportPackage := ReportPackage{
+			Package: newPackage(pkg, settings),
+		}
+		summary.addPackage(reportPackage.Package)
+		for _, tc := range pkg.TestCases {
+			reportPackage.Tests.add(tc.Result)
+		}
+		report.Packages = append(report.Packages, reportPackage)
+	}
+	report.Summary = summary.build(settings)
+	output, err := renderTemplate(templateName, templateText, report)
+	if err != nil || settings.MaxReportSize <= 0 {
+		return output, err
+	}
+	report.MaxOutputLength = settings.MaxReportSize
+	for len(output) > settings.MaxReportSize && report.MaxOutputLength > minOutputLength {
+		report.MaxOutputLength /= 2
+		if output, err = renderTemplate(templateName, templateText, report); err != nil {
+			return nil, err
+		}
+	}
+	if len(output) <= settings.MaxReportSize {
+		return output, nil
+	}
+	cut := settings.MaxReportSize - len(reportTruncatedMarker)
+	if cut < 0 {
+		cut = 0
+	}
+	if newline := bytes.LastIndexByte(output[:cut], '\n'); newline >= 0 {
+		cut = newline
+	}
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return append(output[:cut], reportTruncatedMarker...), nil
+}
+
+// truncateOutput shortens the output to at most limit bytes at a line boundary and adds a marker saying how much has
+// been left out. A limit of 0 or less leaves the output unchanged.
+func truncateOutput(limit int, output string) string {
+	if limit <= 0 || len(output) <= limit {
+		return output
+	}
+	cut := strings.LastIndexByte(output[:limit], '\n')
+	if cut < 0 {
+		cut = limit
+		for cut > 0 && !utf8.RuneStart(output[cut]) {
+			cut--
+		}
+	}
+	return fmt.Sprintf("%s\n… output truncated, %d bytes omitted", output[:cut], len(output)-cut)
+}
@@ -0,0 +1,102 @@
+package renderer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+	"github.com/gotesttools/gotestfmt/v2/renderer"
+)
+
+// TestRenderReportCodeBlocks renders the Markdown reports for a test output containing code fences and colors, and
+// checks that the output cannot break out of its code block.
+func TestRenderReportCodeBlocks(t *testing.T) {
+	result := &parser.ParseResult{
+		Packages: []parser.Package{
+			{
+				Name:   "example.com/markdown",
+				Result: parser.ResultFail,
+				TestCases: []*parser.TestCase{
+					{
+						Name:   "TestMarkdown",
+						Result: parser.ResultFail,
+						Output: "    markdown_test.go:10: \033[0;31munexpected\033[0m output:\n```\n<b>not bold</b>\n````go",
+					},
+				},
+			},
+		},
+	}
+	for _, templateFile := range []string{
+		filepath.Join("..", ".gotestfmt", "github", "step-summary.gotpl"),
+		filepath.Join("..", ".gotestfmt", "buildkite", "annotation.gotpl"),
+	} {
+		templateText, err := os.ReadFile(templateFile)
+		if err != nil {
+			t.Fatalf("failed to read %s (%v)", templateFile, err)
+		}
+		output, err := renderer.RenderReport(filepath.Base(templateFile), templateText, result, renderer.RenderSettings{})
+		if err != nil {
+			t.Fatalf("failed to render %s (%v)", templateFile, err)
+		}
+		expected := "`````\n" +
+			"    markdown_test.go:10: unexpected output:\n```\n<b>not bold</b>\n````go\n" +
+			"`````\n"
+		if !strings.Contains(string(output), expected) {
+			t.Fatalf("the output of %s does not contain the expected code block:\n%s", templateFile, output)
+		}
+		if strings.Contains(string(output), "\033") {
+			t.Fatalf("the output of %s contains ANSI escape sequences:\n%s", templateFile, output)
+		}
+	}
+}
+
+// TestRenderReportMaxSize renders the GitHub step summary for test outputs larger than the maximum report size and
+// checks that the outputs are truncated to fit.
+func TestRenderReportMaxSize(t *testing.T) {
+	templateFile := filepath.Join("..", ".gotestfmt", "github", "step-summary.gotpl")
+	templateText, err := os.ReadFile(templateFile)
+	if err != nil {
+		t.Fatalf("failed to read %s (%v)", templateFile, err)
+	}
+	output := strings.Repeat("    large_test.go:10: a line of output\n", 20000)
+	result := &parser.ParseResult{
+		Packages: []parser.Package{
+			{
+				Name:   "example.com/large",
+				Result: parser.ResultFail,
+				TestCases: []*parser.TestCase{
+					{Name: "TestFirst", Result: parser.ResultFail, Output: output},
+					{Name: "TestSecond", Result: parser.ResultFail, Output: output},
+				},
+			},
+		},
+	}
+	const maxSize = 100 * 1024
+	full, err := renderer.RenderReport("step-summary.gotpl", templateText, result, renderer.RenderSettings{})
+	if err != nil {
+		t.Fatalf("failed to render the report (%v)", err)
+	}
+	if len(full) <= maxSize {
+		t.Fatalf("the report without a limit is only %d bytes long", len(full))
+	}
+	truncated, err := renderer.RenderReport(
+		"step-summary.gotpl",
+		templateText,
+		result,
+		renderer.RenderSettings{MaxReportSize: maxSize},
+	)
+	if err != nil {
+		t.Fatalf("failed to render the report (%v)", err)
+	}
+	if len(truncated) > maxSize {
+		t.Fatalf("the report is %d bytes long, more than the maximum of %d bytes", len(truncated), maxSize)
+	}
+	if strings.Count(string(truncated), "… output truncated") != 2 {
+		t.Fatalf("the outputs of both tests are not marked as truncated:\n%s", truncated)
+	}
+	if !strings.Contains(string(truncated), "TestSecond") {
+		t.Fatalf("the second test is missing from the report:\n%s", truncated)
+	}
+}
@@ -0,0 +1,196 @@
+package renderer
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// Summary contains the overall results of a test run for rendering after all packages have been rendered.
+type Summary struct {
+	// Packages contains the number of packages per result.
+	Packages ResultCounts
+	// Tests contains the number of test cases per result, including subtests.
+	Tests ResultCounts
+	// Duration is the time from the start of the first package to the end of the last package. Packages run in parallel,
+	// so this is usually less than the sum of the package durations.
+	Duration time.Duration
+	// Coverage is the percentage of covered statements in all packages with a coverage profile. It is nil if there
+	// are no coverage profiles, or if a package reported coverage without a profile, because the percentages of the
+	// packages cannot be combined without knowing their number of statements.
+	Coverage *float64
+	// DownloadsFailed indicates that one or more dependency downloads failed.
+	DownloadsFailed bool
+	// FailedPackages lists the packages that failed without a failing test case, e.g. because of a build error.
+	FailedPackages []*parser.Package
+	// FailedTests lists all failed test cases.
+	FailedTests []SummaryTestCase
+	// FlakyTests lists all test cases that both passed and failed in different attempts.
+	FlakyTests []SummaryTestCase
+	// SlowestTests lists the slowest top-level test cases, up to the number configured in the settings.
+	SlowestTests []SummaryTestCase
+	// CoverageViolations lists the packages with a coverage below their minimum coverage.
+	CoverageViolations []CoverageViolation
+
+	Settings RenderSettings
+}
+
+// Failed returns true if any download, package or test has failed, or a package is below its minimum coverage.
+func (s Summary) Failed() bool {
+	return s.DownloadsFailed || s.Packages.Fail > 0 || s.Tests.Fail > 0 || len(s.CoverageViolations) > 0
+}
+
+// FailedExceptFlaky returns true if the test run failed for another reason than failed attempts of flaky tests. This is
+// the case when the exit code is non-zero even with AllowFlaky.
+func (s Summary) FailedExceptFlaky() bool {
+	if s.DownloadsFailed || len(s.FailedPackages) > 0 || len(s.CoverageViolations) > 0 {
+		return true
+	}
+	for _, tc := range s.FailedTests {
+		if !tc.Flaky {
+			return true
+		}
+	}
+	return false
+}
+
+// ResultCounts contains the number of items per result.
+type ResultCounts struct {
+	// Total is the total number of items.
+	Total int
+	// Pass is the number of items that passed.
+	Pass int
+	// Fail is the number of items that failed.
+	Fail int
+	// Skip is the number of items that were skipped.
+	Skip int
+}
+
+func (r *ResultCounts) add(result parser.Result) {
+	r.Total++
+	switch result {
+	case parser.ResultPass:
+		r.Pass++
+	case parser.ResultSkip:
+		r.Skip++
+	default:
+		r.Fail++
+	}
+}
+
+// SummaryTestCase is a test case together with the name of its package.
+type SummaryTestCase struct {
+	*parser.TestCase
+
+	// Package is the name of the package the test case belongs to.
+	Package string
+}
+
+// summaryBuilder collects the data for the summary while the packages are being rendered.
+type summaryBuilder struct {
+	summary           Summary
+	startTime         *time.Time
+	endTime           *time.Time
+	statements        int
+	coveredStatements int
+	// unprofiledCoverage is set if a package reported coverage without a coverage profile.
+	unprofiledCoverage bool
+	tests              []SummaryTestCase
+}
+
+func (b *summaryBuilder) addDownloads(downloads *parser.Downloads) {
+	if downloads.Failed {
+		b.summary.DownloadsFailed = true
+	}
+}
+
+func (b *summaryBuilder) addPackage(renderedPackage Package) {
+	pkg := renderedPackage.Package
+	if renderedPackage.BelowMinCoverage {
+		b.summary.CoverageViolations = append(b.summary.CoverageViolations, CoverageViolation{
+			Package:     pkg.Name,
+			Coverage:    pkg.Coverage,
+			MinCoverage: *renderedPackage.MinCoverage,
+		})
+	}
+	b.summary.Packages.add(pkg.Result)
+	b.addDuration(pkg)
+	b.addCoverage(pkg)
+	failedTests := 0
+	for _, tc := range pkg.TestCases {
+		b.summary.Tests.add(tc.Result)
+		summaryTestCase := SummaryTestCase{
+			TestCase: tc,
+			Package:  pkg.Name,
+		}
+		if tc.Result == parser.ResultFail {
+			b.summary.FailedTests = append(b.summary.FailedTests, summaryTestCase)
+			failedTests++
+		}
+		if tc.Flaky {
+			b.summary.FlakyTests = append(b.summary.FlakyTests, summaryTestCase)
+		}
+		if !strings.Contains(tc.Name, "/") {
+			b.tests = append(b.tests, summaryTestCase)
+		}
+	}
+	if pkg.Result == parser.ResultFail && failedTests == 0 {
+		b.summary.FailedPackages = append(b.summary.FailedPackages, pkg)
+	}
+}
+
+// addDuration extends the time span of the test run to include the package. Packages without a start time only make
+// sure the span is at least as long as their duration.
+func (b *summaryBuilder) addDuration(pkg *parser.Package) {
+	if pkg.StartTime != nil {
+		endTime := pkg.StartTime.Add(pkg.Duration)
+		if b.startTime == nil || pkg.StartTime.Before(*b.startTime) {
+			b.startTime = pkg.StartTime
+		}
+		if b.endTime == nil || endTime.After(*b.endTime) {
+			b.endTime = &endTime
+		}
+	}
+	if b.startTime != nil && b.endTime.Sub(*b.startTime) > b.summary.Duration {
+		b.summary.Duration = b.endTime.Sub(*b.startTime)
+	}
+	if pkg.Duration > b.summary.Duration {
+		b.summary.Duration = pkg.Duration
+	}
+}
+
+// addCoverage adds the statements from the coverage profile of the package to the total coverage.
+func (b *summaryBuilder) addCoverage(pkg *parser.Package) {
+	if len(pkg.CoverageByFile) == 0 {
+		if pkg.Coverage != nil {
+			b.unprofiledCoverage = true
+		}
+		return
+	}
+	for _, file := range pkg.CoverageByFile {
+		b.statements += file.Statements
+		b.coveredStatements += file.CoveredStatements
+	}
+}
+
+func (b *summaryBuilder) build(settings RenderSettings) Summary {
+	summary := b.summary
+	summary.Settings = settings
+	if b.statements > 0 && !b.unprofiledCoverage {
+		coverage := math.Round(float64(b.coveredStatements)/float64(b.statements)*1000) / 10
+		summary.Coverage = &coverage
+	}
+	sort.SliceStable(b.tests, func(i, j int) bool {
+		return b.tests[i].Duration > b.tests[j].Duration
+	})
+	for _, tc := range b.tests {
+		if len(summary.SlowestTests) >= settings.SlowestTests {
+			break
+		}
+		summary.SlowestTests = append(summary.SlowestTests, tc)
+	}
+	return summary
+}
@@ -0,0 +1,289 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+)
+
+// OutputFormat selects how the results are written to the output.
+type OutputFormat string
+
+const (
+	// OutputFormatText renders the results with the templates.
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatTAP writes the results as a Test Anything Protocol stream instead of using the templates.
+	OutputFormatTAP OutputFormat = "tap"
+)
+
+// RenderTAP writes the results as a single TAP version 14 stream. Each package is a test point, its tests are
+// subtests, and subtests of tests are nested further. The duration, the package name and the output are included as a
+// YAML diagnostic block for each test point. Text before the tests is written as comments and failed dependency
+// downloads are reported as failed test points. Since the number of packages is only known at the end, the plan is
+// written after the test points.
+func RenderTAP(
+	prefixes <-chan string,
+	downloadsChannel <-chan *parser.Downloads,
+	packagesChannel <-chan *parser.Package,
+	settings RenderSettings,
+) (<-chan []byte, <-chan int, <-chan error) {
+	if settings.SortPackages {
+		packagesChannel = sortPackages(packagesChannel)
+	}
+	result := make(chan []byte)
+	exitCodeChan := make(chan int, 1)
+	errs := make(chan error, 1)
+	go func() {
+		exitCode := newExitCodeTracker(settings)
+		defer func() {
+			close(result)
+			exitCodeChan <- exitCode.exitCode()
+			close(exitCodeChan)
+			close(errs)
+		}()
+		result <- []byte("TAP version 14\n")
+		for {
+			prefix, ok := <-prefixes
+			if !ok {
+				break
+			}
+			result <- []byte(tapComment("", prefix))
+		}
+
+		testPoint := 0
+		for {
+			downloads, ok := <-downloadsChannel
+			if !ok {
+				break
+			}
+			exitCode.addDownloads(downloads)
+			for _, download := range downloads.Packages {
+				if !download.Failed {
+					continue
+				}
+				testPoint++
+				result <- []byte(tapDownload(testPoint, download))
+			}
+			if downloads.Reason != "" {
+				testPoint++
+				result <- []byte(tapDownload(testPoint, &parser.Download{
+					Package: "dependencies",
+					Failed:  true,
+					Reason:  downloads.Reason,
+				}))
+			}
+		}
+
+		for {
+			pkg, ok := <-packagesChannel
+			if !ok {
+				break
+			}
+			renderedPackage := newPackage(pkg, settings)
+			exitCode.addPackage(renderedPackage)
+			testPoint++
+			result <- []byte(tapPackage(testPoint, renderedPackage))
+		}
+		result <- []byte(fmt.Sprintf("1..%d\n", testPoint))
+	}()
+	return result, exitCodeChan, errs
+}
+
+func tapDownload(number int, download *parser.Download) string {
+	description := "download " + download.Package
+	if download.Version != "" {
+		description += " " + download.Version
+	}
+	return tapTestPoint("", number, false, description, "") + tapDiagnostics("", []tapField{
+		{key: "package", value: download.Package},
+		{key: "message", value: download.Reason},
+	})
+}
+
+func tapPackage(number int, pkg Package) string {
+	text := strings.Builder{}
+	roots := pkg.RootTestCases()
+	if len(roots) > 0 {
+		text.WriteString(tapComment("", "Subtest: "+pkg.Name))
+		for i, tc := range roots {
+			text.WriteString(tapTestCase("    ", i+1, pkg.Name, tc))
+		}
+		text.WriteString(fmt.Sprintf("    1..%d\n", len(roots)))
+	}
+
+	directive := ""
+	if pkg.Result == parser.ResultSkip {
+		directive = "SKIP"
+		if len(roots) == 0 {
+			directive += " no test files"
+		}
+	}
+	ok := pkg.Result != parser.ResultFail && !pkg.BelowMinCoverage
+	text.WriteString(tapTestPoint("", number, ok, pkg.Name, directive))
+	fields := []tapField{
+		{key: "duration_ms", value: tapDuration(pkg.Duration)},
+		{key: "package", value: pkg.Name},
+	}
+	if pkg.Coverage != nil {
+		fields = append(fields, tapField{key: "coverage", value: fmt.Sprintf("%g", *pkg.Coverage)})
+	}
+	if pkg.BelowMinCoverage {
+		fields = append(fields, tapField{key: "min_coverage", value: fmt.Sprintf("%g", *pkg.MinCoverage)})
+	}
+	if pkg.Cached {
+		fields = append(fields, tapField{key: "cached", value: "true"})
+	}
+	fields = append(fields, tapField{key: "message", value: pkg.Reason}, tapOutput(pkg.Output))
+	text.WriteString(tapDiagnostics("", fields))
+	return text.String()
+}
+
+func tapTestCase(indent string, number int, pkg string, tc *parser.TestCase) string {
+	text := strings.Builder{}
+	if len(tc.Children) > 0 {
+		text.WriteString(tapComment(indent, "Subtest: "+tc.ShortName()))
+		for i, child := range tc.Children {
+			text.WriteString(tapTestCase(indent+"    ", i+1, pkg, child))
+		}
+		text.WriteString(fmt.Sprintf("%s    1..%d\n", indent, len(tc.Children)))
+	}
+	directive := ""
+	if tc.Result == parser.ResultSkip {
+		directive = "SKIP"
+		if reason := skipReason(tc.Output); reason != "" {
+			directive += " " + reason
+		}
+	}
+	text.WriteString(tapTestPoint(indent, number, tc.Result != parser.ResultFail, tc.ShortName(), directive))
+	fields := []tapField{
+		{key: "duration_ms", value: tapDuration(tc.Duration)},
+		{key: "package", value: pkg},
+		{key: "test", value: tc.Name},
+	}
+	if tc.Flaky {
+		fields = append(fields, tapField{key: "flaky", value: "true"})
+	}
+	fields = append(fields, tapOutput(tc.Output))
+	text.WriteString(tapDiagnostics(indent, fields))
+	return text.String()
+}
+
+// skipReason returns the last line of the output of a skipped test, usually the message passed to t.Skip.
+func skipReason(output string) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return ""
+	}
+	lines := strings.Split(output, "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+var tapDescriptionReplacer = strings.NewReplacer(
+	"\\", "\\\\",
+	"#", "\\#",
+	"\n", " ",
+)
+
+func tapTestPoint(indent string, number int, ok bool, description string, directive string) string {
+	status := "ok"
+	if !ok {
+		status = "not ok"
+	}
+	line := fmt.Sprintf("%s%s %d - %s", indent, status, number, tapDescriptionReplacer.Replace(description))
+	if directive != "" {
+		line += " # " + strings.Replace(directive, "\n", " ", -1)
+	}
+	return line + "\n"
+}
+
+func tapComment(indent string, text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = indent + "# " + line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func tapDuration(duration time.Duration) string {
+	return fmt.Sprintf("%g", float64(duration)/float64(time.Millisecond))
+}
+
+// tapField is a key-value pair in a YAML diagnostic block.
+type tapField struct {
+	key   string
+	value string
+	// literal writes the value as a literal block, even if it fits on a single line.
+	literal bool
+}
+
+func tapOutput(output string) tapField {
+	return tapField{key: "output", value: output, literal: true}
+}
+
+// tapDiagnostics returns the YAML diagnostic block for a test point. Empty values are left out.
+func tapDiagnostics(indent string, fields []tapField) string {
+	text := strings.Builder{}
+	text.WriteString(indent + "  ---\n")
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		text.WriteString(indent + "  " + field.key + ": " + yamlValue(indent+"    ", field.value, field.literal) + "\n")
+	}
+	text.WriteString(indent + "  ...\n")
+	return text.String()
+}
+
+// yamlValue formats a value for the YAML diagnostic block. Multi-line and indented values are written as literal blocks,
+// other values that are not safe as plain YAML scalars are quoted.
+func yamlValue(indent string, value string, literal bool) string {
+	if !yamlPrintable(value) {
+		// JSON strings are valid double-quoted YAML scalars.
+		quoted, _ := json.Marshal(value)
+		return string(quoted)
+	}
+	if literal || strings.Contains(value, "\n") || strings.HasPrefix(value, " ") {
+		lines := strings.Split(strings.TrimRight(value, "\n"), "\n")
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = indent + line
+			}
+		}
+		header := "|-"
+		if strings.HasPrefix(value, " ") {
+			// The indentation must be explicit if the first line starts with a space.
+			header = "|2-"
+		}
+		return header + "\n" + strings.Join(lines, "\n")
+	}
+	if yamlPlainSafe(value) {
+		return value
+	}
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
+// yamlPrintable returns false if the value contains characters that cannot appear in a YAML literal block.
+func yamlPrintable(value string) bool {
+	for _, r := range value {
+		if (r < 0x20 && r != '\n' && r != '\t') || r == 0x7f || r == '\uFEFF' || r == '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// yamlPlainSafe returns true if the single-line value can be written as a plain YAML scalar without changing its
+// meaning, which is only checked conservatively.
+func yamlPlainSafe(value string) bool {
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, ":#\t") {
+		return false
+	}
+	if strings.ContainsAny(value[:1], "-?,[]{}&*!|>'\"%@`") {
+		return false
+	}
+	return true
+}
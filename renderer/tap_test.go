@@ -0,0 +1,80 @@
+package renderer_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+	"github.com/gotesttools/gotestfmt/v2/renderer"
+	"github.com/gotesttools/gotestfmt/v2/tokenizer"
+)
+
+// tapSettings contains the render settings for the TAP test cases that do not use the defaults.
+var tapSettings = map[string]renderer.RenderSettings{
+	"packages": {
+		CoverageThresholds: []renderer.CoverageThreshold{
+			{Pattern: "example.com/covered", MinCoverage: 50},
+			{Pattern: "example.com/uncovered/...", MinCoverage: 50},
+		},
+	},
+}
+
+// TestRenderTAP runs the *.txt files in the testdata directory through the tokenizer, the parser and the TAP renderer
+// and compares the result with the *.tap files.
+func TestRenderTAP(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatalf("failed to list test inputs (%v)", err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no test inputs found in the testdata directory")
+	}
+	for _, input := range inputs {
+		input := input
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			actual := renderTAP(t, input, tapSettings[name])
+			expectedFile := strings.TrimSuffix(input, ".txt") + ".tap"
+			expected, err := os.ReadFile(expectedFile)
+			if err != nil {
+				t.Logf("Failed to open test expectation, dumping actual result in %s...", expectedFile+".actual")
+				if err := os.WriteFile(expectedFile+".actual", actual, 0644); err != nil {
+					t.Fatalf("Failed to write %s (%v)", expectedFile+".actual", err)
+				}
+				t.Skipf("Failed to open test expectation: %s (%v)", expectedFile, err)
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("The expected output did not match the real output:\n%s\n(expected:\n%s)", actual, expected)
+			}
+		})
+	}
+}
+
+func renderTAP(t *testing.T, inputFile string, settings renderer.RenderSettings) []byte {
+	fh, err := os.Open(inputFile)
+	if err != nil {
+		t.Fatalf("Failed to open test input: %s (%v)", inputFile, err)
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	prefixes, downloads, packages := parser.Parse(tokenizer.Tokenize(fh))
+	settings.SortPackages = true
+	result, exitCodes, errs := renderer.RenderTAP(prefixes, downloads, packages, settings)
+	output := &bytes.Buffer{}
+	for {
+		fragment, ok := <-result
+		if !ok {
+			break
+		}
+		output.Write(fragment)
+	}
+	<-exitCodes
+	if err := <-errs; err != nil {
+		t.Fatalf("Failed to render TAP output (%v)", err)
+	}
+	return output.Bytes()
+}
@@ -0,0 +1,25 @@
+package renderer_test
+
+import (
+	"testing"
+
+	"github.com/gotesttools/gotestfmt/v2/renderer"
+)
+
+// TestEscapeTeamCity checks that the characters with a special meaning in TeamCity service messages are escaped.
+func TestEscapeTeamCity(t *testing.T) {
+	for value, expected := range map[string]string{
+		"plain":              "plain",
+		"a|b":                "a||b",
+		"it's":               "it|'s",
+		"[a]":                "|[a|]",
+		"line\nnext":         "line|nnext",
+		"carriage\rreturn":   "carriage|rreturn",
+		"\u0085\u2028\u2029": "|x|l|p",
+		"|'[]\n":             "|||'|[|]|n",
+	} {
+		if actual := renderer.EscapeTeamCity(value); actual != expected {
+			t.Fatalf("unexpected escaping of %q: %q (expected: %q)", value, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package renderer_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gotesttools/gotestfmt/v2/parser"
+	"github.com/gotesttools/gotestfmt/v2/renderer"
+	"github.com/gotesttools/gotestfmt/v2/tokenizer"
+)
+
+// templateSettings contains the render settings for the template test cases that do not use the defaults.
+var templateSettings = map[string]renderer.RenderSettings{
+	"azure/flaky": {AllowFlaky: true},
+}
+
+// reportTemplates contains the report template for the CI systems that write a Markdown report in addition to the
+// normal output.
+var reportTemplates = map[string]string{
+	"buildkite": "annotation.gotpl",
+}
+
+// TestRenderTemplates runs the *.txt files in the subdirectories of the testdata directory through the tokenizer, the
+// parser and the templates in the .gotestfmt directory of the same name, and compares the result with the *.out files.
+// If the CI system has a report template, the report is compared with the *.md files.
+// The files in the default directory are rendered with the templates in the .gotestfmt directory itself.
+func TestRenderTemplates(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*", "*.txt"))
+	if err != nil {
+		t.Fatalf("failed to list test inputs (%v)", err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no test inputs found in the testdata directory")
+	}
+	for _, input := range inputs {
+		input := input
+		ci := filepath.Base(filepath.Dir(input))
+		name := ci + "/" + strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			templateDir := filepath.Join("..", ".gotestfmt", ci)
+			if ci == "default" {
+				templateDir = filepath.Join("..", ".gotestfmt")
+			}
+			actual := renderTemplates(t, input, templateDir, templateSettings[name])
+			compareGolden(t, strings.TrimSuffix(input, ".txt")+".out", actual)
+			if reportTemplate, ok := reportTemplates[ci]; ok {
+				report := renderReport(t, input, filepath.Join(templateDir, reportTemplate), templateSettings[name])
+				compareGolden(t, strings.TrimSuffix(input, ".txt")+".md", report)
+			}
+		})
+	}
+}
+
+func renderTemplates(t *testing.T, inputFile string, templateDir string, settings renderer.RenderSettings) []byte {
+	templates := map[string][]byte{}
+	for _, name := range []string{"downloads.gotpl", "package.gotpl", "summary.gotpl"} {
+		templateText, err := os.ReadFile(filepath.Join(templateDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read template %s (%v)", name, err)
+		}
+		templates[name] = templateText
+	}
+	fh, err := os.Open(inputFile)
+	if err != nil {
+		t.Fatalf("Failed to open test input: %s (%v)", inputFile, err)
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	prefixes, downloads, packages := parser.Parse(tokenizer.Tokenize(fh))
+	settings.SortPackages = true
+	result, exitCodes, errs := renderer.RenderWithSettingsAndErrors(
+		prefixes,
+		downloads,
+		packages,
+		templates["downloads.gotpl"],
+		templates["package.gotpl"],
+		templates["summary.gotpl"],
+		settings,
+	)
+	output := &bytes.Buffer{}
+	for {
+		fragment, ok := <-result
+		if !ok {
+			break
+		}
+		output.Write(fragment)
+	}
+	<-exitCodes
+	if err := <-errs; err != nil {
+		t.Fatalf("Failed to render the templates (%v)", err)
+	}
+	return output.Bytes()
+}
+
+// compareGolden compares the actual output with the expected file. If the expected file does not exist, the actual
+// output is written next to it and the test is skipped.
+func compareGolden(t *testing.T, expectedFile string, actual []byte) {
+	expected, err := os.ReadFile(expectedFile)
+	if err != nil {
+		t.Logf("Failed to open test expectation, dumping actual result in %s...", expectedFile+".actual")
+		if err := os.WriteFile(expectedFile+".actual", actual, 0644); err != nil {
+			t.Fatalf("Failed to write %s (%v)", expectedFile+".actual", err)
+		}
+		t.Skipf("Failed to open test expectation: %s (%v)", expectedFile, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("The expected output did not match the real output:\n%s\n(expected:\n%s)", actual, expected)
+	}
+}
+
+func renderReport(t *testing.T, inputFile string, templateFile string, settings renderer.RenderSettings) []byte {
+	templateText, err := os.ReadFile(templateFile)
+	if err != nil {
+		t.Fatalf("Failed to read template %s (%v)", templateFile, err)
+	}
+	fh, err := os.Open(inputFile)
+	if err != nil {
+		t.Fatalf("Failed to open test input: %s (%v)", inputFile, err)
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	prefixes, downloads, packages := parser.Parse(tokenizer.Tokenize(fh))
+	result := &parser.ParseResult{}
+	for {
+		prefix, ok := <-prefixes
+		if !ok {
+			break
+		}
+		result.Prefix = append(result.Prefix, prefix)
+	}
+	for {
+		dl, ok := <-downloads
+		if !ok {
+			break
+		}
+		result.Downloads = *dl
+	}
+	for {
+		pkg, ok := <-packages
+		if !ok {
+			break
+		}
+		result.Packages = append(result.Packages, *pkg)
+	}
+	settings.SortPackages = true
+	output, err := renderer.RenderReport(filepath.Base(templateFile), templateText, result, settings)
+	if err != nil {
+		t.Fatalf("Failed to render the report (%v)", err)
+	}
+	return output
+}
@@ -3,6 +3,8 @@ package tokenizer
 type Action string
 
 const (
+	// ActionStart declares that the test binary of a package has been started.
+	ActionStart Action = "start"
 	// ActionRun declares that a test has been started.
 	ActionRun Action = "run"
 	// ActionPause declares that a test was paused.
@@ -35,4 +37,19 @@ const (
 	ActionFailFinal Action = "fail-final"
 	// ActionSkipFinal is a final SKIP line to indicate the entire package has failed.
 	ActionSkipFinal Action = "skip-final"
+	// ActionBench is an event when a benchmark printed log output without failing. The contents are in the output field.
+	ActionBench Action = "bench"
+	// ActionBenchmark is an event when a benchmark reported a result line, such as
+	// "BenchmarkFoo-8  1000  1234 ns/op  56 B/op  2 allocs/op". The whole line is in the output field. Only sent for
+	// go test -json output, because the text output does not say which package the benchmark belongs to.
+	ActionBenchmark Action = "benchmark"
+	// ActionFuzz is an event when a fuzz test reported its progress while fuzzing, such as
+	// "fuzz: elapsed: 3s, execs: 1234 (411/sec), new interesting: 2 (total: 12)". The whole line is in the output field.
+	// Only sent for go test -json output.
+	ActionFuzz Action = "fuzz"
+	// ActionBuildOutput is an event when the go command printed build output, such as a compile error. The import path
+	// of the package being built is in the import path field, the contents are in the output field.
+	ActionBuildOutput Action = "build-output"
+	// ActionBuildFail declares that the build of the package in the import path field failed.
+	ActionBuildFail Action = "build-fail"
 )
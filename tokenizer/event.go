@@ -29,6 +29,11 @@ type Event struct {
 	Coverage *float64 `json:"coverage"`
 	// JSON indicates if the event was sent using a JSON-encoded test.
 	JSON bool `json:"json"`
+	// ImportPath is the import path of the package being built for build events. Test variants of a package have the
+	// test binary in brackets, such as "example.com/foo [example.com/foo.test]".
+	ImportPath string `json:"importPath"`
+	// FailedBuild is the import path of the package whose build failure caused a package-level failure.
+	FailedBuild string `json:"failedBuild"`
 }
 
 func (e Event) Equals(o Event) bool {
@@ -39,6 +44,8 @@ func (e Event) Equals(o Event) bool {
 		e.Elapsed == o.Elapsed &&
 		e.Cached == o.Cached &&
 		e.JSON == o.JSON &&
+		e.ImportPath == o.ImportPath &&
+		e.FailedBuild == o.FailedBuild &&
 		(e.Coverage == o.Coverage ||
 			(e.Coverage != nil && o.Coverage != nil && *e.Coverage == *o.Coverage)) &&
 		bytes.Equal(e.Output, o.Output)
@@ -71,6 +78,10 @@ type tmpEvent struct {
 	Coverage *float64 `json:"coverage"`
 	// JSON indicates that the event was JSON-encoded.
 	JSON bool `json:"json"`
+	// ImportPath is the import path of the package being built for build events.
+	ImportPath string `json:"importPath"`
+	// FailedBuild is the import path of the package whose build failure caused a package-level failure.
+	FailedBuild string `json:"failedBuild"`
 }
 
 func (e *Event) UnmarshalJSON(data []byte) error {
@@ -97,20 +108,24 @@ func (e *Event) UnmarshalJSON(data []byte) error {
 	e.Cached = tmp.Cached
 	e.Coverage = tmp.Coverage
 	e.JSON = tmp.JSON
+	e.ImportPath = tmp.ImportPath
+	e.FailedBuild = tmp.FailedBuild
 	return nil
 }
 
 func (e *Event) MarshalJSON() ([]byte, error) {
 	tmp := tmpEvent{
-		Action:   e.Action,
-		Package:  e.Package,
-		Version:  e.Version,
-		Test:     e.Test,
-		Elapsed:  e.Elapsed.String(),
-		Output:   e.Output,
-		Cached:   e.Cached,
-		Coverage: e.Coverage,
-		JSON:     e.JSON,
+		Action:      e.Action,
+		Package:     e.Package,
+		Version:     e.Version,
+		Test:        e.Test,
+		Elapsed:     e.Elapsed.String(),
+		Output:      e.Output,
+		Cached:      e.Cached,
+		Coverage:    e.Coverage,
+		JSON:        e.JSON,
+		ImportPath:  e.ImportPath,
+		FailedBuild: e.FailedBuild,
 	}
 	return json.Marshal(tmp)
 }
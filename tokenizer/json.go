@@ -0,0 +1,299 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// jsonActions maps the test2json actions to the tokenizer actions. The "output" action is handled separately because
+// the output may contain the package summary lines.
+var jsonActions = map[string]Action{
+	"start":        ActionStart,
+	"run":          ActionRun,
+	"pause":        ActionPause,
+	"cont":         ActionCont,
+	"pass":         ActionPass,
+	"fail":         ActionFail,
+	"skip":         ActionSkip,
+	"bench":        ActionBench,
+	"build-output": ActionBuildOutput,
+	"build-fail":   ActionBuildFail,
+}
+
+// jsonTestFrameRegexp matches the lines test2json has already converted into actions, such as "=== RUN" or
+// "--- PASS:". The first or the second group contains the test name.
+var jsonTestFrameRegexp = regexp.MustCompile(
+	`^(?:=== (?:RUN|PAUSE|CONT|NAME)\s+([^\s]+)\s*|\s*--- (?:PASS|FAIL|SKIP):\s+([^\s]+) \([^\s]*\))$`,
+)
+
+// jsonPackageFrameRegexp matches the package-level result lines without a test name.
+var jsonPackageFrameRegexp = regexp.MustCompile(`^(PASS|FAIL|SKIP)$`)
+
+// benchmarkResultRegexp matches the result line of a benchmark, including the custom metrics reported with
+// b.ReportMetric.
+var benchmarkResultRegexp = regexp.MustCompile(`^Benchmark[^\s]*\s+[0-9]+(?:\s+[-+0-9.eE]+ [^\s]+)+\s*$`)
+
+// fuzzProgressRegexp matches the progress lines printed while fuzzing with -fuzz.
+var fuzzProgressRegexp = regexp.MustCompile(`^fuzz: `)
+
+// jsonPackageSummaries are the package summary lines that carry extra information about the package result. The
+// information is attached to the package-level pass, fail or skip action that follows them.
+var jsonPackageSummaries = []*regexp.Regexp{
+	regexp.MustCompile(`^ok\s+(?P<Package>[^\s]+)\s+(\((?P<Cached>cached)\)|(?P<Elapsed>[^\s]*))(|\s+coverage: ((?P<Coverage>[0-9.]+)% of statements.*|\[no statements]))$`),
+	regexp.MustCompile(`^FAIL\s+(?P<Package>[^\s]+)\s+\[(?P<Output>.*)]$`),
+	regexp.MustCompile(`^FAIL\s+(?P<Package>[^\s]+)\s+\(?(?P<Elapsed>[^\s]*?)\)?$`),
+	regexp.MustCompile(`^SKIP\s+(?P<Package>[^\s]+)\s+\(((?P<Elapsed>[^\s]*)|(?P<Cached>cached))\)$`),
+	regexp.MustCompile(`^\?\s+(?P<Package>[^\s]+)\s+\[(?P<Output>.*)]$`),
+}
+
+// jsonCoverageLines are the standalone coverage lines within a package output.
+var jsonCoverageLines = map[Action]*regexp.Regexp{
+	ActionCoverage:             regexp.MustCompile(`^coverage: (?P<Coverage>[0-9.]+)% of statements.*$`),
+	ActionCoverageNoStatements: regexp.MustCompile(`^coverage: \[no statements]$`),
+}
+
+// jsonState holds the information that spans several test2json events.
+type jsonState struct {
+	// summaries holds the package summaries by package name.
+	summaries map[string]*packageSummary
+	// partialOutputs holds the output not terminated by a newline yet by package and test name. Newer Go versions
+	// split benchmark result lines into the name and the results this way.
+	partialOutputs map[jsonOutputKey]string
+}
+
+type jsonOutputKey struct {
+	pkg  string
+	test string
+}
+
+func newJSONState() *jsonState {
+	return &jsonState{
+		summaries:      map[string]*packageSummary{},
+		partialOutputs: map[jsonOutputKey]string{},
+	}
+}
+
+// packageSummary holds the information from the package summary line until the package-level action arrives.
+type packageSummary struct {
+	cached   bool
+	coverage *float64
+	output   []byte
+}
+
+type jsonTestEvent struct {
+	Time    *time.Time `json:",omitempty"`
+	Action  string
+	Package string   `json:",omitempty"`
+	Test    string   `json:",omitempty"`
+	Elapsed *float64 `json:",omitempty"`
+	Output  *string  `json:",omitempty"`
+	// OutputType is set to "frame" by newer Go versions on lines that test2json converted into actions.
+	OutputType string `json:",omitempty"`
+	// ImportPath is set on the build events of newer Go versions instead of the package.
+	ImportPath string `json:",omitempty"`
+	// FailedBuild is set on the package-level fail action if the package failed because of a build error.
+	FailedBuild string `json:",omitempty"`
+}
+
+func tryParseJSONLine(line []byte) *jsonTestEvent {
+	if len(line) == 0 || line[0] != 123 {
+		return nil
+	}
+
+	// Try to decode JSON line
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	jsonLine := &jsonTestEvent{}
+	if err := decoder.Decode(jsonLine); err != nil {
+		return nil
+	}
+	return jsonLine
+}
+
+// parseJSONLine converts a test2json event into a tokenizer event based on its Action field. Output lines are only
+// inspected to remove the lines test2json has already turned into actions and to pick up the package summary and
+// benchmark results.
+func parseJSONLine(jsonLine *jsonTestEvent, state *jsonState, output chan<- Event) {
+	evt := Event{
+		Received:    time.Now(),
+		Package:     jsonLine.Package,
+		Test:        jsonLine.Test,
+		JSON:        true,
+		ImportPath:  jsonLine.ImportPath,
+		FailedBuild: jsonLine.FailedBuild,
+	}
+	if jsonLine.Time != nil {
+		evt.Received = *jsonLine.Time
+	}
+	if jsonLine.Elapsed != nil {
+		evt.Elapsed = time.Duration(math.Round(*jsonLine.Elapsed*1000000)) * time.Microsecond
+	}
+	key := jsonOutputKey{pkg: jsonLine.Package, test: jsonLine.Test}
+	var line []byte
+	if jsonLine.Output != nil {
+		line = []byte(strings.TrimRight(*jsonLine.Output, "\r\n"))
+	}
+
+	action, ok := jsonActions[jsonLine.Action]
+	if !ok {
+		// This is an "output" action, or an action we don't know yet that may still carry output.
+		if jsonLine.Output == nil {
+			return
+		}
+		if jsonLine.OutputType == "frame" {
+			// Frames are not sent, but the package summary line, such as "FAIL foo [build failed]", is a frame in
+			// newer Go versions.
+			if evt.Test == "" {
+				parseJSONOutput(&evt, line, state.summaries)
+			}
+			return
+		}
+		text := state.partialOutputs[key] + *jsonLine.Output
+		if !strings.HasSuffix(text, "\n") {
+			state.partialOutputs[key] = text
+			return
+		}
+		delete(state.partialOutputs, key)
+		if !parseJSONOutput(&evt, []byte(strings.TrimRight(text, "\r\n")), state.summaries) {
+			return
+		}
+		output <- evt
+		return
+	}
+	state.flush(key, evt.Received, output)
+	evt.Action = action
+	switch action {
+	case ActionPass, ActionFail, ActionSkip:
+		if evt.Test == "" {
+			if summary, ok := state.summaries[evt.Package]; ok {
+				evt.Cached = summary.cached
+				evt.Coverage = summary.coverage
+				evt.Output = summary.output
+				delete(state.summaries, evt.Package)
+			}
+		}
+	case ActionBench:
+		if jsonLine.Output == nil {
+			return
+		}
+		evt.Output = line
+		if benchmarkResultRegexp.Match(line) {
+			evt.Action = ActionBenchmark
+		}
+	case ActionBuildOutput:
+		if jsonLine.Output == nil {
+			return
+		}
+		evt.Output = line
+	}
+	output <- evt
+}
+
+// flush sends the unterminated output of a test before the next action of the test is sent.
+func (s *jsonState) flush(key jsonOutputKey, received time.Time, output chan<- Event) {
+	text, ok := s.partialOutputs[key]
+	if !ok {
+		return
+	}
+	delete(s.partialOutputs, key)
+	evt := Event{
+		Received: received,
+		Package:  key.pkg,
+		Test:     key.test,
+		JSON:     true,
+	}
+	if parseJSONOutput(&evt, []byte(strings.TrimRight(text, "\r\n")), s.summaries) {
+		output <- evt
+	}
+}
+
+// flushAll sends all unterminated outputs at the end of the input.
+func (s *jsonState) flushAll(output chan<- Event) {
+	keys := make([]jsonOutputKey, 0, len(s.partialOutputs))
+	for key := range s.partialOutputs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pkg != keys[j].pkg {
+			return keys[i].pkg < keys[j].pkg
+		}
+		return keys[i].test < keys[j].test
+	})
+	for _, key := range keys {
+		s.flush(key, time.Now(), output)
+	}
+}
+
+// isTestFrame returns true if the line is a frame of the test, which older Go versions send without an OutputType.
+// Lines that only look like a frame of another test, for example because the test printed them, are not frames.
+func isTestFrame(line []byte, test string) bool {
+	match := jsonTestFrameRegexp.FindSubmatch(line)
+	if match == nil {
+		return false
+	}
+	name := match[1]
+	if name == nil {
+		name = match[2]
+	}
+	return test != "" && string(name) == test
+}
+
+// parseJSONOutput fills the event from an output line. It returns false if the line should not be sent.
+func parseJSONOutput(evt *Event, line []byte, summaries map[string]*packageSummary) bool {
+	if isTestFrame(line, evt.Test) {
+		return false
+	}
+	if evt.Test != "" && strings.HasPrefix(evt.Test, "Benchmark") {
+		// Benchmarks print their name before running, followed by the result line once they finish.
+		if string(line) == evt.Test {
+			return false
+		}
+		if benchmarkResultRegexp.Match(line) {
+			evt.Action = ActionBenchmark
+			evt.Output = line
+			return true
+		}
+	}
+	if evt.Test != "" && strings.HasPrefix(evt.Test, "Fuzz") && fuzzProgressRegexp.Match(line) {
+		evt.Action = ActionFuzz
+		evt.Output = line
+		return true
+	}
+	if evt.Test == "" {
+		if jsonPackageFrameRegexp.Match(line) {
+			return false
+		}
+		for _, r := range jsonPackageSummaries {
+			if match := r.FindSubmatch(line); len(match) != 0 {
+				coverage, err := getCoverage(r, match)
+				if err != nil {
+					continue
+				}
+				summaries[evt.Package] = &packageSummary{
+					cached:   string(extract(r, match, "Cached")) == "cached",
+					coverage: coverage,
+					output:   extract(r, match, "Output"),
+				}
+				return false
+			}
+		}
+		for action, r := range jsonCoverageLines {
+			if match := r.FindSubmatch(line); len(match) != 0 {
+				coverage, err := getCoverage(r, match)
+				if err != nil {
+					continue
+				}
+				evt.Action = action
+				evt.Coverage = coverage
+				return true
+			}
+		}
+	}
+	evt.Action = ActionStdout
+	evt.Output = line
+	return true
+}
@@ -2,24 +2,36 @@ package tokenizer
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 )
 
 // Tokenize starts a reader of Event in the background that reads until the input is closed. This method starts a
-// goroutine in the background and should be stopped by closing the input reader.
+// goroutine in the background and should be stopped by closing the input reader. If reading the input fails the
+// output channel is closed. Use TokenizeWithErrors to receive the error.
 func Tokenize(input io.Reader) <-chan Event {
-	output := make(chan Event)
-	go decode(input, output)
+	output, _ := TokenizeWithErrors(input)
 	return output
 }
 
+// TokenizeWithErrors works like Tokenize, but also returns an error channel. The error channel receives at most one
+// error if reading the input fails and is closed after the output channel has been closed.
+func TokenizeWithErrors(input io.Reader) (<-chan Event, <-chan error) {
+	output := make(chan Event)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		if err := decode(input, output); err != nil {
+			errs <- err
+		}
+	}()
+	return output, errs
+}
+
 type state string
 
 const (
@@ -362,16 +374,17 @@ var stateMachine = []stateChange{
 	},
 }
 
-func decode(input io.Reader, output chan<- Event) {
+func decode(input io.Reader, output chan<- Event) error {
 	defer close(output)
 	var lastBuffer []byte
 	buffer := make([]byte, 4096)
 	currentState := stateInit
+	jsonState := newJSONState()
 	for {
 		n, err := input.Read(buffer)
 		if err != nil {
 			if !errors.Is(err, io.EOF) {
-				panic(fmt.Errorf("failed to read from input (%w)", err))
+				return fmt.Errorf("failed to read from input (%w)", err)
 			}
 			break
 		}
@@ -384,33 +397,18 @@ func decode(input io.Reader, output chan<- Event) {
 		lines = lines[:len(lines)-1]
 		for _, line := range lines {
 			line = bytes.TrimSuffix(line, []byte("\r"))
-			currentState = parseLine(currentState, line, output)
+			currentState = parseLine(currentState, line, jsonState, output)
 		}
 	}
-	_ = parseLine(currentState, lastBuffer, output)
+	_ = parseLine(currentState, lastBuffer, jsonState, output)
+	jsonState.flushAll(output)
+	return nil
 }
 
-func tryParseJSONLine(line []byte) *jsonTestEvent {
-	if len(line) == 0 || line[0] != 123 {
-		return nil
-	}
-
-	// Try to decode JSON line
-	decoder := json.NewDecoder(bytes.NewReader(line))
-	jsonLine := &jsonTestEvent{}
-	if err := decoder.Decode(jsonLine); err != nil {
-		return nil
-	}
-	return jsonLine
-}
-
-func parseLine(currentState state, line []byte, output chan<- Event) state {
-	jsonLine := tryParseJSONLine(line)
-	if jsonLine != nil {
-		if jsonLine.Output == nil {
-			return currentState
-		}
-		line = []byte(strings.TrimRight(*jsonLine.Output, "\r\n"))
+func parseLine(currentState state, line []byte, jsonState *jsonState, output chan<- Event) state {
+	if jsonLine := tryParseJSONLine(line); jsonLine != nil {
+		parseJSONLine(jsonLine, jsonState, output)
+		return stateBetweenTests
 	}
 
 	for _, stateTransition := range stateMachine {
@@ -418,55 +416,23 @@ func parseLine(currentState state, line []byte, output chan<- Event) state {
 			continue
 		}
 
-		// Don't match the "package" action against a JSON lines - issue #52
-		if jsonLine != nil && stateTransition.action == ActionPackage {
-			continue
-		}
-
 		if match := stateTransition.regexp.FindSubmatch(line); len(match) != 0 {
 			elapsed, err := getTimeElapsed(stateTransition.regexp, match, "Elapsed")
 			if err == nil {
-				coverageString := string(extract(stateTransition.regexp, match, "Coverage"))
-				coverage := -1.0
-				if coverageString != "" {
-					coverage, err = strconv.ParseFloat(coverageString, 64)
-					if err != nil {
-						continue
-					}
-				}
-				var coveragePtr *float64
-				if coverage >= 0 {
-					coveragePtr = &coverage
-				}
-
-				pkg := string(extract(stateTransition.regexp, match, "Package"))
-				if jsonLine != nil && pkg == "" {
-					pkg = jsonLine.Package
-				}
-				version := string(extract(stateTransition.regexp, match, "Version"))
-				test := string(extract(stateTransition.regexp, match, "Test"))
-				if jsonLine != nil && test == "" {
-					test = jsonLine.Test
-				}
-				cached := string(extract(stateTransition.regexp, match, "Cached")) == "cached"
-				received := time.Now()
-				if jsonLine != nil && jsonLine.Time != nil {
-					received = *jsonLine.Time
-				}
-				if jsonLine != nil && jsonLine.Elapsed != nil && *jsonLine.Elapsed > 0 {
-					elapsed = time.Duration(*jsonLine.Elapsed * float64(time.Second))
+				coverage, err := getCoverage(stateTransition.regexp, match)
+				if err != nil {
+					continue
 				}
 				evt := Event{
-					Received: received,
+					Received: time.Now(),
 					Action:   stateTransition.action,
-					Package:  pkg,
-					Version:  version,
-					Test:     test,
-					Cached:   cached,
-					Coverage: coveragePtr,
+					Package:  string(extract(stateTransition.regexp, match, "Package")),
+					Version:  string(extract(stateTransition.regexp, match, "Version")),
+					Test:     string(extract(stateTransition.regexp, match, "Test")),
+					Cached:   string(extract(stateTransition.regexp, match, "Cached")) == "cached",
+					Coverage: coverage,
 					Elapsed:  elapsed,
 					Output:   extract(stateTransition.regexp, match, "Output"),
-					JSON:     jsonLine != nil,
 				}
 
 				output <- evt
@@ -475,11 +441,28 @@ func parseLine(currentState state, line []byte, output chan<- Event) state {
 		}
 	}
 	if len(line) != 0 {
-		panic(fmt.Errorf("failed to match line: %v", line))
+		// None of the rules matched, keep the line as raw output.
+		output <- Event{
+			Received: time.Now(),
+			Action:   ActionStdout,
+			Output:   line,
+		}
 	}
 	return currentState
 }
 
+func getCoverage(r *regexp.Regexp, match [][]byte) (*float64, error) {
+	coverageString := string(extract(r, match, "Coverage"))
+	if coverageString == "" {
+		return nil, nil
+	}
+	coverage, err := strconv.ParseFloat(coverageString, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &coverage, nil
+}
+
 func getTimeElapsed(r *regexp.Regexp, match [][]byte, name string) (time.Duration, error) {
 	val := extract(r, match, name)
 	if val == nil {
@@ -495,12 +478,3 @@ func extract(r *regexp.Regexp, match [][]byte, name string) []byte {
 	}
 	return match[idx]
 }
-
-type jsonTestEvent struct {
-	Time    *time.Time `json:",omitempty"`
-	Action  string
-	Package string   `json:",omitempty"`
-	Test    string   `json:",omitempty"`
-	Elapsed *float64 `json:",omitempty"`
-	Output  *string  `json:",omitempty"`
-}